@@ -15,17 +15,30 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"runtime/debug"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/audit"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/auth"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/certs"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/clusters"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/diagnostics"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/health"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/identity"
 	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
 	publication "github.com/Netcracker/pgskipper-replication-controller/pkg/publicaion"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/slots"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/subscriptions"
 	"github.com/Netcracker/pgskipper-replication-controller/pkg/users"
 	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/basicauth"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	fiberUtils "github.com/gofiber/fiber/v2/utils"
 	"go.uber.org/zap"
@@ -35,18 +48,53 @@ const (
 	pgDB            = "postgres"
 	publicationPath = "/publications"
 	usersPath       = "/users"
+	slotsPath       = "/slots"
+	subsPath        = "/subscriptions"
+	diagnosticsPath = "/diagnostics"
+	identityPath    = "/identity"
+	auditPath       = "/audit"
+	clustersPath    = "/clusters"
 
-	httpsPort = 8443
+	defaultServePass = "logical-repl-password"
 )
 
 var (
-	pgHost = flag.String("pg_host", utils.GetEnv("POSTGRES_HOST", "127.0.0.1"), "Host of PostgreSQL cluster, env: POSTGRES_HOST")
+	pgHost = flag.String("pg_host", utils.GetEnv("POSTGRES_HOST", "127.0.0.1"), "Host of PostgreSQL cluster or comma separated hosts with optional ports, primary is discovered among them, env: POSTGRES_HOST")
 	pgPort = flag.Int("pg_port", utils.GetEnvInt("POSTGRES_PORT", 5432), "Port of PostgreSQL cluster, env: POSTGRES_PORT")
 	pgUser = flag.String("pg_user", utils.GetEnv("POSTGRES_ADMIN_USER", "postgres"), "Username of controller user in PostgreSQL, env: POSTGRES_ADMIN_USER")
 	pgPass = flag.String("pg_pass", utils.GetEnv("POSTGRES_ADMIN_PASSWORD", ""), "Password of controller user in PostgreSQL, env: POSTGRES_ADMIN_PASSWORD")
-	pgSsl  = flag.String("pg_ssl", utils.GetEnv("PG_SSL", "off"), "Enable ssl connection to postgreSQL, env: PG_SSL")
+	pgSsl  = flag.String("pg_ssl", utils.GetEnv("PG_SSL", "off"), "Enable ssl connection to postgreSQL, deprecated in favor of pg_sslmode, env: PG_SSL")
+
+	pgSslMode     = flag.String("pg_sslmode", utils.GetEnv("PG_SSLMODE", ""), "libpq sslmode of postgreSQL connections, pg_ssl is used if empty, env: PG_SSLMODE")
+	pgSslRootCert = flag.String("pg_sslrootcert", utils.GetEnv("PG_SSLROOTCERT", ""), "Path of CA bundle to verify postgreSQL server certificate, env: PG_SSLROOTCERT")
+	pgSslCert     = flag.String("pg_sslcert", utils.GetEnv("PG_SSLCERT", ""), "Path of client certificate for postgreSQL, env: PG_SSLCERT")
+	pgSslKey      = flag.String("pg_sslkey", utils.GetEnv("PG_SSLKEY", ""), "Path of client certificate key for postgreSQL, env: PG_SSLKEY")
+
+	subPgHost = flag.String("sub_pg_host", utils.GetEnv("SUBSCRIBER_POSTGRES_HOST", ""), "Host or comma separated hosts of subscriber PostgreSQL cluster, publisher cluster is used if empty, env: SUBSCRIBER_POSTGRES_HOST")
+	subPgPort = flag.Int("sub_pg_port", utils.GetEnvInt("SUBSCRIBER_POSTGRES_PORT", 5432), "Port of subscriber PostgreSQL cluster, env: SUBSCRIBER_POSTGRES_PORT")
+	subPgUser = flag.String("sub_pg_user", utils.GetEnv("SUBSCRIBER_POSTGRES_ADMIN_USER", "postgres"), "Username of controller user in subscriber PostgreSQL, env: SUBSCRIBER_POSTGRES_ADMIN_USER")
+	subPgPass = flag.String("sub_pg_pass", utils.GetEnv("SUBSCRIBER_POSTGRES_ADMIN_PASSWORD", ""), "Password of controller user in subscriber PostgreSQL, env: SUBSCRIBER_POSTGRES_ADMIN_PASSWORD")
+	subPgSsl  = flag.String("sub_pg_ssl", utils.GetEnv("SUBSCRIBER_PG_SSL", "off"), "Enable ssl connection to subscriber postgreSQL, deprecated in favor of sub_pg_sslmode, env: SUBSCRIBER_PG_SSL")
+
+	subPgSslMode     = flag.String("sub_pg_sslmode", utils.GetEnv("SUBSCRIBER_PG_SSLMODE", ""), "libpq sslmode of subscriber postgreSQL connections, sub_pg_ssl is used if empty, env: SUBSCRIBER_PG_SSLMODE")
+	subPgSslRootCert = flag.String("sub_pg_sslrootcert", utils.GetEnv("SUBSCRIBER_PG_SSLROOTCERT", ""), "Path of CA bundle to verify subscriber postgreSQL server certificate, env: SUBSCRIBER_PG_SSLROOTCERT")
+	subPgSslCert     = flag.String("sub_pg_sslcert", utils.GetEnv("SUBSCRIBER_PG_SSLCERT", ""), "Path of client certificate for subscriber postgreSQL, env: SUBSCRIBER_PG_SSLCERT")
+	subPgSslKey      = flag.String("sub_pg_sslkey", utils.GetEnv("SUBSCRIBER_PG_SSLKEY", ""), "Path of client certificate key for subscriber postgreSQL, env: SUBSCRIBER_PG_SSLKEY")
+
+	patroniUrls     = flag.String("patroni_urls", utils.GetEnv("PATRONI_URLS", ""), "Comma separated Patroni REST API URLs to resolve leader, configured hosts are used if empty, env: PATRONI_URLS")
+	patroniCacheTTL = flag.Int("patroni_cache_sec", utils.GetEnvInt("PATRONI_CACHE_SEC", 30), "Duration of Patroni leader caching in seconds, env: PATRONI_CACHE_SEC")
+	patroniTimeout  = flag.Int("patroni_timeout_sec", utils.GetEnvInt("PATRONI_TIMEOUT_SEC", 5), "Timeout of Patroni REST API requests in seconds, env: PATRONI_TIMEOUT_SEC")
+	subPatroniUrls  = flag.String("sub_patroni_urls", utils.GetEnv("SUBSCRIBER_PATRONI_URLS", ""), "Comma separated Patroni REST API URLs of subscriber cluster, env: SUBSCRIBER_PATRONI_URLS")
 
 	servePort = flag.Int("serve_port", 8080, "Port to serve requests incoming to controller")
+	httpsPort = flag.Int("https_port", utils.GetEnvInt("HTTPS_PORT", 8443), "Port to serve TLS requests incoming to controller, env: HTTPS_PORT")
+
+	tlsCertFile       = flag.String("tls_cert_file", utils.GetEnv("TLS_CERT_FILE", "/certs/tls.crt"), "Path of server certificate, env: TLS_CERT_FILE")
+	tlsKeyFile        = flag.String("tls_key_file", utils.GetEnv("TLS_KEY_FILE", "/certs/tls.key"), "Path of server certificate key, env: TLS_KEY_FILE")
+	tlsClientCAFile   = flag.String("tls_client_ca_file", utils.GetEnv("TLS_CLIENT_CA_FILE", ""), "Path of CA bundle to verify client certificates, mTLS is disabled if empty, env: TLS_CLIENT_CA_FILE")
+	mtlsRequired      = flag.Bool("mtls_required", utils.GetEnvBool("MTLS_REQUIRED", false), "Reject TLS connections without client certificate, env: MTLS_REQUIRED")
+	tlsReloadInterval = flag.Int("tls_reload_interval_sec", utils.GetEnvInt("TLS_RELOAD_INTERVAL_SEC", 30), "Interval of certificate files change checks in seconds, env: TLS_RELOAD_INTERVAL_SEC")
+
 	serveUser = flag.String(
 		"server_user",
 		utils.GetEnv("API_USER", "logical-repl-user"),
@@ -54,9 +102,42 @@ var (
 	)
 	servePass = flag.String(
 		"server_pass",
-		utils.GetEnv("API_PASSWORD", "logical-repl-password"),
+		utils.GetEnv("API_PASSWORD", defaultServePass),
 		"Password to authorize incoming requests, env: API_PASSWORD",
 	)
+	principalsFile = flag.String(
+		"api_principals_file",
+		utils.GetEnv("API_PRINCIPALS_FILE", ""),
+		"Path of JSON file with API principals, server user is used if empty, env: API_PRINCIPALS_FILE",
+	)
+
+	slotGuardEnabled     = flag.Bool("slot_guard_enabled", utils.GetEnvBool("SLOT_GUARD_ENABLED", false), "Enable background protection against runaway inactive slots, env: SLOT_GUARD_ENABLED")
+	slotGuardInterval    = flag.Int("slot_guard_interval_sec", utils.GetEnvInt("SLOT_GUARD_INTERVAL_SEC", 60), "Interval of slot guard checks in seconds, env: SLOT_GUARD_INTERVAL_SEC")
+	slotGuardMaxInactive = flag.Int("slot_guard_max_inactive_sec", utils.GetEnvInt("SLOT_GUARD_MAX_INACTIVE_SEC", 0), "Max duration of slot inactivity in seconds, 0 disables check, env: SLOT_GUARD_MAX_INACTIVE_SEC")
+	slotGuardMaxRetained = flag.Int("slot_guard_max_retained_bytes", utils.GetEnvInt("SLOT_GUARD_MAX_RETAINED_BYTES", 0), "Max WAL retained by slot in bytes, 0 disables check, env: SLOT_GUARD_MAX_RETAINED_BYTES")
+	slotGuardPolicy      = flag.String("slot_guard_policy", utils.GetEnv("SLOT_GUARD_POLICY", slots.PolicyLog), "Action for violating slots: log, event or drop, env: SLOT_GUARD_POLICY")
+	slotGuardProtected   = flag.String("slot_guard_protected", utils.GetEnv("SLOT_GUARD_PROTECTED", ""), "Comma separated names of slots ignored by slot guard, env: SLOT_GUARD_PROTECTED")
+	slotGuardPattern     = flag.String("slot_guard_protected_pattern", utils.GetEnv("SLOT_GUARD_PROTECTED_PATTERN", ""), "Regexp of slot names ignored by slot guard, env: SLOT_GUARD_PROTECTED_PATTERN")
+	slotGuardEventUrl    = flag.String("slot_guard_event_url", utils.GetEnv("SLOT_GUARD_EVENT_URL", ""), "URL to post slot guard events to, env: SLOT_GUARD_EVENT_URL")
+
+	healthInterval = flag.Int("health_interval_sec", utils.GetEnvInt("HEALTH_INTERVAL_SEC", 10), "Interval of background health checks in seconds, env: HEALTH_INTERVAL_SEC")
+	healthTimeout  = flag.Int("health_timeout_sec", utils.GetEnvInt("HEALTH_TIMEOUT_SEC", 5), "Timeout of single health check in seconds, env: HEALTH_TIMEOUT_SEC")
+
+	jwtIssuer         = flag.String("jwt_issuer", utils.GetEnv("JWT_ISSUER", ""), "Issuer of accepted JWT bearer tokens, JWT authentication is disabled if empty, env: JWT_ISSUER")
+	jwtAudience       = flag.String("jwt_audience", utils.GetEnv("JWT_AUDIENCE", ""), "Required audience of JWT bearer tokens, not checked if empty, env: JWT_AUDIENCE")
+	jwtJwksFile       = flag.String("jwt_jwks_file", utils.GetEnv("JWT_JWKS_FILE", ""), "Path of JWKS file with token signing keys, env: JWT_JWKS_FILE")
+	jwtJwksUrl        = flag.String("jwt_jwks_url", utils.GetEnv("JWT_JWKS_URL", ""), "URL of JWKS with token signing keys, env: JWT_JWKS_URL")
+	jwtJwksRefresh    = flag.Int("jwt_jwks_refresh_sec", utils.GetEnvInt("JWT_JWKS_REFRESH_SEC", 600), "Interval of JWKS reload in seconds, env: JWT_JWKS_REFRESH_SEC")
+	jwtSubjectClaim   = flag.String("jwt_subject_claim", utils.GetEnv("JWT_SUBJECT_CLAIM", "sub"), "Claim with principal name, env: JWT_SUBJECT_CLAIM")
+	jwtRolesClaim     = flag.String("jwt_roles_claim", utils.GetEnv("JWT_ROLES_CLAIM", "roles"), "Claim with controller roles, env: JWT_ROLES_CLAIM")
+	jwtClustersClaim  = flag.String("jwt_clusters_claim", utils.GetEnv("JWT_CLUSTERS_CLAIM", "clusters"), "Claim with allowed clusters, all clusters are allowed if absent, env: JWT_CLUSTERS_CLAIM")
+	jwtDatabasesClaim = flag.String("jwt_databases_claim", utils.GetEnv("JWT_DATABASES_CLAIM", "databases"), "Claim with allowed databases as name for default cluster or cluster/database, tokens without it are rejected unless they have cluster-admin role, env: JWT_DATABASES_CLAIM")
+
+	auditStorage = flag.String("audit_storage", utils.GetEnv("AUDIT_STORAGE", audit.StorageTable), "Storage of audit trail: table, file or none, env: AUDIT_STORAGE")
+	auditFile    = flag.String("audit_file", utils.GetEnv("AUDIT_FILE", "audit.jsonl"), "Path of JSONL audit file for file storage, env: AUDIT_FILE")
+
+	clustersFile  = flag.String("clusters_file", utils.GetEnv("CLUSTERS_FILE", ""), "Path of JSON file with additional managed clusters, env: CLUSTERS_FILE")
+	clustersState = flag.String("clusters_state_file", utils.GetEnv("CLUSTERS_STATE_FILE", ""), "Path of writable file, where clusters registered with API are stored, they are lost on restart if empty, env: CLUSTERS_STATE_FILE")
 
 	log      = utils.GetLogger()
 	pgClient *postgres.Client
@@ -66,34 +147,222 @@ func main() {
 	flag.Parse()
 	log.Debug("Controller started")
 
-	app := fiber.New(fiber.Config{Network: "tcp"})
+	app := fiber.New(fiber.Config{Network: "tcp", ErrorHandler: apierror.ErrorHandler})
 
-	app.Get("/health", HealthHandler)
-	setAuth(app)
+	pgTLS := getTLSConfig("postgreSQL", *pgSsl, *pgSslMode, *pgSslRootCert, *pgSslCert, *pgSslKey)
+	pgClient = postgres.NewClient(*pgHost, *pgPort, *pgUser, *pgPass, pgDB, pgTLS)
+	setPatroniResolver(pgClient, *patroniUrls)
+	subClient := getSubscriberClient()
 
 	setRecovery(app)
 
-	pgClient = postgres.NewClient(*pgHost, *pgPort, *pgUser, *pgPass, pgDB, *pgSsl)
+	// Health endpoints are registered before auth to be available for probes
+	healthChecker := runHealthChecker(subClient)
+	app.Get("/health", healthChecker.ReadyHandler)
+	app.Get("/health/live", healthChecker.LiveHandler)
+	app.Get("/health/ready", healthChecker.ReadyHandler)
+	authenticator := setAuth(app)
+	read := authenticator.Require(auth.RoleReadOnly)
+	pubAdmin := authenticator.Require(auth.RolePublicationAdmin)
+	// Slots are addressed by name and REPLICATION is granted for cluster, so database limits don't apply
+	slotAdmin := authenticator.RequireClusterWide(auth.RolePublicationAdmin)
+	userAdmin := authenticator.RequireClusterWide(auth.RoleUserAdmin)
+	clusterAdmin := authenticator.Require(auth.RoleClusterAdmin)
+
+	auditor := setAudit(app, read)
+
+	app.Get("/pools", read, PoolStatsHandler)
+
+	registry := getClusterRegistry(subClient)
+	clustersGroup := app.Group(clustersPath)
+	clustersGroup.Get("/", read, registry.ClusterListHandler)
+	clustersGroup.Get("/:cluster", read, registry.ClusterGetHandler)
+	clustersGroup.Post("/", clusterAdmin, registry.ClusterCreateHandler)
+	clustersGroup.Delete("/:cluster", clusterAdmin, registry.ClusterDeleteHandler)
+	if auditor != nil {
+		clustersGroup.Get("/:cluster"+auditPath, read, auditor.AuditListHandler)
+	}
+
+	// Routes without cluster id are served by default cluster
+	setClusterRoutes(app, registry, read, pubAdmin, slotAdmin, userAdmin)
+	setClusterRoutes(clustersGroup.Group("/:cluster"), registry, read, pubAdmin, slotAdmin, userAdmin)
 
-	pubController := publication.NewPublicationController(pgClient)
-	pubGroup := app.Group(publicationPath, func(c *fiber.Ctx) error {
+	if *slotGuardEnabled {
+		runSlotGuard()
+	}
+
+	log.Fatal("Controller has been stopped", zap.Error(RunFiberServer(app)))
+}
+
+func getSubscriberClient() *postgres.Client {
+	if len(*subPgHost) == 0 {
+		return pgClient
+	}
+	subTLS := getTLSConfig("subscriber postgreSQL", *subPgSsl, *subPgSslMode, *subPgSslRootCert, *subPgSslCert, *subPgSslKey)
+	subClient := postgres.NewClient(*subPgHost, *subPgPort, *subPgUser, *subPgPass, pgDB, subTLS)
+	setPatroniResolver(subClient, *subPatroniUrls)
+	return subClient
+}
+
+func setPatroniResolver(client *postgres.Client, urls string) {
+	if len(urls) == 0 {
+		return
+	}
+	resolver := postgres.NewPatroniResolver(splitList(urls), time.Duration(*patroniCacheTTL)*time.Second, time.Duration(*patroniTimeout)*time.Second)
+	client.SetResolver(resolver)
+	log.Info(fmt.Sprintf("Postgres leader is resolved with Patroni %s", urls))
+}
+
+func getTLSConfig(name string, ssl, mode, rootCert, cert, key string) postgres.TLSConfig {
+	tlsConfig := postgres.NewTLSConfig(ssl, mode, rootCert, cert, key)
+	if err := tlsConfig.Validate(); err != nil {
+		log.Fatal(fmt.Sprintf("TLS configuration of %s connections is invalid", name), zap.Error(err))
+	}
+	return tlsConfig
+}
+
+// Clusters from file are registered along with default cluster configured by flags
+func getClusterRegistry(subClient *postgres.Client) *clusters.Registry {
+	defaultCluster := clusters.NewDefaultCluster(pgClient, subClient, splitList(*patroniUrls))
+	registry := clusters.NewRegistry(defaultCluster, time.Duration(*patroniCacheTTL)*time.Second, time.Duration(*patroniTimeout)*time.Second)
+	if len(*clustersFile) > 0 {
+		configs, err := clusters.LoadClusters(*clustersFile)
+		if err != nil {
+			log.Fatal("Clusters cannot be loaded", zap.Error(err))
+		}
+		for _, config := range configs {
+			if err = registry.Add(config, clusters.SourceFile); err != nil {
+				log.Fatal(fmt.Sprintf("Cluster %s cannot be registered", config.Id), zap.Error(err))
+			}
+		}
+	}
+	if len(*clustersState) > 0 {
+		if err := registry.SetStateFile(*clustersState); err != nil {
+			log.Fatal("Clusters registered with API cannot be loaded", zap.Error(err))
+		}
+	}
+	return registry
+}
+
+func setClusterRoutes(router fiber.Router, registry *clusters.Registry, read, pubAdmin, slotAdmin, userAdmin fiber.Handler) {
+	pub := registry.Publications
+	pubGroup := router.Group(publicationPath, func(c *fiber.Ctx) error {
 		//Common API Handler
 		return c.Next()
 	})
-	pubGroup.Get("/:database/:publication", pubController.PublicationGetHandler)
-	pubGroup.Post("/create", pubController.PublicationCreateHandler)
-	pubGroup.Post("/alter/add", pubController.PublicationAlterAddHandler)
-	pubGroup.Post("/alter/set", pubController.PublicationAlterSetHandler)
-	pubGroup.Delete("/drop", pubController.PublicationDropHandler)
-
-	userController := users.NewUsersController(pgClient)
-	usersGroup := app.Group(usersPath, func(c *fiber.Ctx) error {
+	pubGroup.Get("/", read, pub((*publication.PublicationController).PublicationInventoryHandler))
+	pubGroup.Get("/:database", read, pub((*publication.PublicationController).PublicationListHandler))
+	pubGroup.Get("/:database/:publication", read, pub((*publication.PublicationController).PublicationGetHandler))
+	pubGroup.Put("/:database/:publication", pubAdmin, pub((*publication.PublicationController).PublicationApplyHandler))
+	pubGroup.Post("/create", pubAdmin, pub((*publication.PublicationController).PublicationCreateHandler))
+	pubGroup.Post("/alter/add", pubAdmin, pub((*publication.PublicationController).PublicationAlterAddHandler))
+	pubGroup.Post("/alter/set", pubAdmin, pub((*publication.PublicationController).PublicationAlterSetHandler))
+	pubGroup.Post("/alter/drop", pubAdmin, pub((*publication.PublicationController).PublicationAlterDropHandler))
+	pubGroup.Delete("/drop", pubAdmin, pub((*publication.PublicationController).PublicationDropHandler))
+
+	usersGroup := router.Group(usersPath, func(c *fiber.Ctx) error {
 		//Common API Handler
 		return c.Next()
 	})
-	usersGroup.Post("/grant", userController.GrantUserHandler)
+	usersGroup.Post("/grant", userAdmin, registry.Users((*users.UsersController).GrantUserHandler))
 
-	log.Fatal("Controller has been stopped", zap.Error(RunFiberServer(app)))
+	slot := registry.Slots
+	slotsGroup := router.Group(slotsPath, func(c *fiber.Ctx) error {
+		//Common API Handler
+		return c.Next()
+	})
+	slotsGroup.Get("/", read, slot((*slots.SlotController).SlotListHandler))
+	slotsGroup.Get("/lag", read, slot((*slots.SlotController).SlotLagHandler))
+	slotsGroup.Post("/create", pubAdmin, slot((*slots.SlotController).SlotCreateHandler))
+	slotsGroup.Post("/advance", slotAdmin, slot((*slots.SlotController).SlotAdvanceHandler))
+	slotsGroup.Delete("/drop", slotAdmin, slot((*slots.SlotController).SlotDropHandler))
+
+	sub := registry.Subscriptions
+	subsGroup := router.Group(subsPath, func(c *fiber.Ctx) error {
+		//Common API Handler
+		return c.Next()
+	})
+	subsGroup.Get("/:database", read, sub((*subscriptions.SubscriptionController).SubscriptionListHandler))
+	subsGroup.Get("/:database/:subscription", read, sub((*subscriptions.SubscriptionController).SubscriptionGetHandler))
+	subsGroup.Get("/:database/:subscription/status", read, sub((*subscriptions.SubscriptionController).SubscriptionStatusHandler))
+	subsGroup.Post("/create", pubAdmin, sub((*subscriptions.SubscriptionController).SubscriptionCreateHandler))
+	subsGroup.Post("/alter", pubAdmin, sub((*subscriptions.SubscriptionController).SubscriptionAlterHandler))
+	subsGroup.Delete("/drop", pubAdmin, sub((*subscriptions.SubscriptionController).SubscriptionDropHandler))
+
+	ident := registry.Identity
+	identityGroup := router.Group(identityPath, func(c *fiber.Ctx) error {
+		//Common API Handler
+		return c.Next()
+	})
+	identityGroup.Get("/:database", read, ident((*identity.IdentityController).IdentityListHandler))
+	identityGroup.Get("/:database/:table", read, ident((*identity.IdentityController).IdentityGetHandler))
+	identityGroup.Post("/set", pubAdmin, ident((*identity.IdentityController).IdentitySetHandler))
+
+	diagGroup := router.Group(diagnosticsPath, func(c *fiber.Ctx) error {
+		//Common API Handler
+		return c.Next()
+	})
+	diagGroup.Get("/:database", read, registry.Diagnostics((*diagnostics.DiagnosticsController).DiagnosticsHandler))
+}
+
+func runHealthChecker(subClient *postgres.Client) *health.Checker {
+	checker := health.NewChecker(time.Duration(*healthInterval)*time.Second, time.Duration(*healthTimeout)*time.Second)
+	checker.Register("postgres", pgClient.CheckHealth)
+	if subClient != pgClient {
+		checker.Register("subscriberPostgres", subClient.CheckHealth)
+	}
+	go checker.Run(context.Background())
+	return checker
+}
+
+func runSlotGuard() {
+	guard, err := slots.NewSlotGuard(pgClient, slots.GuardConfig{
+		Interval:         time.Duration(*slotGuardInterval) * time.Second,
+		MaxInactive:      time.Duration(*slotGuardMaxInactive) * time.Second,
+		MaxRetainedBytes: int64(*slotGuardMaxRetained),
+		Policy:           *slotGuardPolicy,
+		Protected:        splitList(*slotGuardProtected),
+		ProtectedPattern: *slotGuardPattern,
+		EventUrl:         *slotGuardEventUrl,
+	})
+	if err != nil {
+		log.Fatal("Slot guard cannot be started", zap.Error(err))
+	}
+	go guard.Run(context.Background())
+}
+
+func splitList(list string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Audit of default cluster is served without cluster id, auditor is nil if audit is disabled
+func setAudit(app *fiber.App, read fiber.Handler) *audit.Auditor {
+	var store audit.Store
+	switch *auditStorage {
+	case audit.StorageNone:
+		log.Warn("Audit trail is disabled")
+		return nil
+	case audit.StorageTable:
+		store = audit.NewTableStore(pgClient)
+	case audit.StorageFile:
+		fileStore, err := audit.NewFileStore(*auditFile)
+		if err != nil {
+			log.Fatal("Audit trail cannot be initialized", zap.Error(err))
+		}
+		store = fileStore
+	default:
+		log.Fatal(fmt.Sprintf("Audit storage %s is not supported, allowed values: table, file, none", *auditStorage))
+	}
+	auditor := audit.NewAuditor(store)
+	app.Use(auditor.Middleware)
+	app.Get(auditPath, read, auditor.AuditListHandler)
+	return auditor
 }
 
 func setRecovery(app *fiber.App) {
@@ -111,17 +380,54 @@ func setRecovery(app *fiber.App) {
 	})
 }
 
-func setAuth(app *fiber.App) {
-	app.Use(basicauth.New(basicauth.Config{
-		Users: map[string]string{
-			*serveUser: *servePass,
-		},
-	}))
+// Principals are loaded from file if it is set, otherwise single
+// server user is configured with all roles for compatibility.
+// Bearer tokens are accepted along with basic auth if JWT issuer is set
+func setAuth(app *fiber.App) *auth.Authenticator {
+	var principals []auth.Principal
+	var err error
+	if len(*principalsFile) > 0 {
+		principals, err = auth.LoadPrincipals(*principalsFile)
+	} else {
+		log.Warn("API principals file is not set, single API user is configured with all roles")
+		if *servePass == defaultServePass {
+			log.Warn("API user has default password, it must be changed")
+		}
+		var principal auth.Principal
+		principal, err = auth.NewPrincipal(*serveUser, *servePass, auth.RolePublicationAdmin, auth.RoleUserAdmin, auth.RoleClusterAdmin)
+		principals = []auth.Principal{principal}
+	}
+	if err != nil {
+		log.Fatal("API principals cannot be loaded", zap.Error(err))
+	}
+	var jwtValidator *auth.JWTValidator
+	if len(*jwtIssuer) > 0 {
+		jwtValidator, err = auth.NewJWTValidator(auth.JWTConfig{
+			Issuer:         *jwtIssuer,
+			Audience:       *jwtAudience,
+			JWKSFile:       *jwtJwksFile,
+			JWKSUrl:        *jwtJwksUrl,
+			JWKSRefresh:    time.Duration(*jwtJwksRefresh) * time.Second,
+			SubjectClaim:   *jwtSubjectClaim,
+			RolesClaim:     *jwtRolesClaim,
+			ClustersClaim:  *jwtClustersClaim,
+			DatabasesClaim: *jwtDatabasesClaim,
+		})
+		if err != nil {
+			log.Fatal("JWT authentication cannot be configured", zap.Error(err))
+		}
+		log.Info(fmt.Sprintf("JWT authentication is enabled for issuer %s", *jwtIssuer))
+	}
+	authenticator, err := auth.NewAuthenticator(principals, jwtValidator)
+	if err != nil {
+		log.Fatal("API principals are invalid", zap.Error(err))
+	}
+	app.Use(authenticator.Middleware)
+	return authenticator
 }
 
-func HealthHandler(c *fiber.Ctx) error {
-	pgClient.RequestHealth()
-	return nil
+func PoolStatsHandler(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(pgClient.PoolStats())
 }
 
 func RunFiberServer(app *fiber.App) error {
@@ -131,9 +437,21 @@ func RunFiberServer(app *fiber.App) error {
 	return app.Listen(":" + strconv.Itoa(*servePort))
 }
 
+// Certificates are reloaded on change, client certificates are verified
+// against client CA bundle if it is set
 func runServerTLS(app *fiber.App) {
-	err := app.ListenTLS(":"+strconv.Itoa(httpsPort), "/certs/tls.crt", "/certs/tls.key")
+	reloader, err := certs.NewReloader(*tlsCertFile, *tlsKeyFile, *tlsClientCAFile, time.Duration(*tlsReloadInterval)*time.Second)
+	if err != nil {
+		log.Fatal("TLS certificates cannot be loaded", zap.Error(err))
+	}
+	go reloader.Run(context.Background())
+
+	listener, err := tls.Listen("tcp", ":"+strconv.Itoa(*httpsPort), reloader.TLSConfig(*mtlsRequired))
+	if err != nil {
+		log.Fatal("TLS listener cannot be started", zap.Error(err))
+	}
+	err = app.Listener(listener)
 	if err != nil {
-		log.Fatal("error during server execution")
+		log.Fatal("error during server execution", zap.Error(err))
 	}
 }
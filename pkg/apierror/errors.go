@@ -0,0 +1,223 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgconn"
+	"go.uber.org/zap"
+)
+
+const requestIdHeader = "X-Request-ID"
+
+var (
+	log = utils.GetLogger()
+
+	conflictCodes = []string{
+		"42710", // duplicate_object
+		"42P04", // duplicate_database
+		"42P06", // duplicate_schema
+		"42P07", // duplicate_table
+		"42712", // duplicate_alias
+		"42723", // duplicate_function
+		"55006", // object_in_use
+	}
+	notFoundCodes = []string{
+		"42704", // undefined_object
+		"42P01", // undefined_table
+		"42703", // undefined_column
+		"42883", // undefined_function
+		"3D000", // invalid_catalog_name
+		"3F000", // invalid_schema_name
+	}
+	forbiddenCodes = []string{
+		"42501", // insufficient_privilege
+	}
+	unavailableCodes = []string{
+		"28000", // invalid_authorization_specification
+		"28P01", // invalid_password
+		"53300", // too_many_connections
+		"57P01", // admin_shutdown
+		"57P02", // crash_shutdown
+		"57P03", // cannot_connect_now
+	}
+)
+
+// Error is an error of controller operation with HTTP status.
+// Code and Detail are filled for errors originated from PostgreSQL
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Detail  string
+	Err     error
+}
+
+// Body is a JSON representation of Error in responses
+type Body struct {
+	Status    int    `json:"status"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+	Detail    string `json:"detail,omitempty"`
+	RequestId string `json:"requestId,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil && e.Err.Error() != e.Message {
+		return fmt.Sprintf("%s: %s", e.Message, e.Err.Error())
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(status int, format string, args ...interface{}) *Error {
+	return &Error{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+func BadRequest(format string, args ...interface{}) *Error {
+	return New(fiber.StatusBadRequest, format, args...)
+}
+
+func NotFound(format string, args ...interface{}) *Error {
+	return New(fiber.StatusNotFound, format, args...)
+}
+
+func Conflict(format string, args ...interface{}) *Error {
+	return New(fiber.StatusConflict, format, args...)
+}
+
+// FromDB converts error of PostgreSQL interaction to Error with
+// HTTP status according to SQLSTATE code or connection failure
+func FromDB(err error, format string, args ...interface{}) *Error {
+	return fromDB(err, fiber.StatusInternalServerError, fmt.Sprintf(format, args...))
+}
+
+// FromConnection is FromDB for errors of connection establishing,
+// which are reported as unavailability unless SQLSTATE says otherwise
+func FromConnection(err error, format string, args ...interface{}) *Error {
+	return fromDB(err, fiber.StatusServiceUnavailable, fmt.Sprintf(format, args...))
+}
+
+func fromDB(err error, defaultStatus int, message string) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return &Error{
+			Status:  statusBySqlState(pgErr.Code),
+			Code:    pgErr.Code,
+			Message: fmt.Sprintf("%s: %s", message, pgErr.Message),
+			Detail:  pgErr.Detail,
+			Err:     err,
+		}
+	}
+
+	if isConnectionError(err) {
+		return &Error{Status: fiber.StatusServiceUnavailable, Message: message, Detail: err.Error(), Err: err}
+	}
+	return &Error{Status: defaultStatus, Message: message, Detail: err.Error(), Err: err}
+}
+
+func IsNotFound(err error) bool {
+	return HasStatus(err, fiber.StatusNotFound)
+}
+
+func HasStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Status == status
+}
+
+// ErrorHandler is a fiber error handler, which sends errors as JSON Body
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	body := Body{
+		Status:    fiber.StatusInternalServerError,
+		Message:   err.Error(),
+		RequestId: getRequestId(c),
+	}
+
+	var apiErr *Error
+	var fiberErr *fiber.Error
+	if errors.As(err, &apiErr) {
+		body.Status = apiErr.Status
+		body.Code = apiErr.Code
+		body.Message = apiErr.Message
+		body.Detail = apiErr.Detail
+	} else if errors.As(err, &fiberErr) {
+		body.Status = fiberErr.Code
+		body.Message = fiberErr.Message
+	}
+
+	if body.Status >= fiber.StatusInternalServerError {
+		log.Error(fmt.Sprintf("Request %s %s failed", c.Method(), c.Path()),
+			zap.String("request_id", body.RequestId), zap.Error(err))
+	}
+	return c.Status(body.Status).JSON(body)
+}
+
+func getRequestId(c *fiber.Ctx) string {
+	if requestId := c.GetRespHeader(requestIdHeader); len(requestId) > 0 {
+		return requestId
+	}
+	return c.Get(requestIdHeader)
+}
+
+func statusBySqlState(code string) int {
+	switch {
+	case contains(conflictCodes, code):
+		return fiber.StatusConflict
+	case contains(notFoundCodes, code):
+		return fiber.StatusNotFound
+	case contains(forbiddenCodes, code):
+		return fiber.StatusForbidden
+	case contains(unavailableCodes, code), strings.HasPrefix(code, "08"):
+		return fiber.StatusServiceUnavailable
+	// Syntax errors, access rule violations and data exceptions
+	case strings.HasPrefix(code, "42"), strings.HasPrefix(code, "22"):
+		return fiber.StatusBadRequest
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
+
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err)
+}
+
+func contains(codes []string, code string) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
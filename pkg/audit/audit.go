@@ -0,0 +1,119 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	StorageNone  = "none"
+	StorageFile  = "file"
+	StorageTable = "table"
+)
+
+var log = utils.GetLogger()
+
+type recordKey struct{}
+
+// Entry is an audit record of one mutating request
+type Entry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Principal  string    `json:"principal"`
+	RequestId  string    `json:"requestId"`
+	Method     string    `json:"method"`
+	Endpoint   string    `json:"endpoint"`
+	Cluster    string    `json:"cluster"`
+	Database   string    `json:"database,omitempty"`
+	Object     string    `json:"object,omitempty"`
+	DryRun     bool      `json:"dryRun,omitempty"`
+	Statements []string  `json:"statements"`
+	Status     int       `json:"status"`
+	Outcome    string    `json:"outcome"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type Filter struct {
+	From      time.Time
+	To        time.Time
+	Principal string
+	Cluster   string
+	Database  string
+	Object    string
+	Endpoint  string
+	Outcome   string
+	Limit     int
+}
+
+// Store persists audit entries, Query returns the most recent entries first
+type Store interface {
+	Append(ctx context.Context, entry Entry) error
+	Query(ctx context.Context, filter Filter) ([]Entry, error)
+}
+
+// record collects statements executed during request
+type record struct {
+	mutex      sync.Mutex
+	statements []string
+}
+
+func withRecord(ctx context.Context, rec *record) context.Context {
+	return context.WithValue(ctx, recordKey{}, rec)
+}
+
+// AddStatement registers SQL executed for request in its audit entry,
+// it does nothing if ctx doesn't belong to audited request
+func AddStatement(ctx context.Context, sql string, args ...interface{}) {
+	rec, ok := ctx.Value(recordKey{}).(*record)
+	if !ok {
+		return
+	}
+	if len(args) > 0 {
+		params := make([]string, 0, len(args))
+		for i, arg := range args {
+			params = append(params, fmt.Sprintf("$%d=%v", i+1, arg))
+		}
+		sql = fmt.Sprintf("%s -- %s", sql, strings.Join(params, ", "))
+	}
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	rec.statements = append(rec.statements, sql)
+}
+
+func (r *record) getStatements() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append(make([]string, 0, len(r.statements)), r.statements...)
+}
+
+func (f Filter) matches(entry Entry) bool {
+	return (f.From.IsZero() || !entry.Timestamp.Before(f.From)) &&
+		(f.To.IsZero() || entry.Timestamp.Before(f.To)) &&
+		(len(f.Principal) == 0 || entry.Principal == f.Principal) &&
+		(len(f.Cluster) == 0 || entry.Cluster == f.Cluster) &&
+		(len(f.Database) == 0 || entry.Database == f.Database) &&
+		(len(f.Object) == 0 || entry.Object == f.Object) &&
+		(len(f.Endpoint) == 0 || strings.HasPrefix(entry.Endpoint, f.Endpoint)) &&
+		(len(f.Outcome) == 0 || entry.Outcome == f.Outcome)
+}
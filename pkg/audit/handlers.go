@@ -0,0 +1,164 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+var (
+	// Keys of request body and route params naming the changed object
+	objectKeys = []string{"publicationName", "subscriptionName", "slotName", "username", "table", "publication"}
+)
+
+type Auditor struct {
+	store Store
+}
+
+func NewAuditor(store Store) *Auditor {
+	return &Auditor{store: store}
+}
+
+// Middleware records every mutating request after it is handled,
+// failure of audit write is logged and doesn't change response
+func (a *Auditor) Middleware(c *fiber.Ctx) error {
+	method := c.Method()
+	if method != fiber.MethodPost && method != fiber.MethodPut && method != fiber.MethodDelete && method != fiber.MethodPatch {
+		return c.Next()
+	}
+	rec := &record{}
+	c.SetUserContext(withRecord(c.UserContext(), rec))
+	timestamp := time.Now().UTC()
+
+	err := c.Next()
+
+	entry := Entry{
+		Timestamp:  timestamp,
+		Principal:  utils.GetPrincipal(c),
+		RequestId:  c.GetRespHeader("X-Request-ID", c.Get("X-Request-ID")),
+		Method:     method,
+		Endpoint:   c.Path(),
+		Cluster:    utils.GetRequestCluster(c),
+		DryRun:     c.QueryBool("dryRun"),
+		Statements: rec.getStatements(),
+		Status:     c.Response().StatusCode(),
+		Outcome:    OutcomeSuccess,
+	}
+	entry.Database, entry.Object = getTarget(c)
+	if err != nil {
+		entry.Status = getErrorStatus(err)
+		entry.Outcome = OutcomeFailure
+		entry.Error = err.Error()
+	}
+	if appendErr := a.store.Append(context.Background(), entry); appendErr != nil {
+		log.Error(fmt.Sprintf("Audit entry of request %s cannot be recorded", entry.RequestId), zap.Error(appendErr))
+	}
+	return err
+}
+
+func (a *Auditor) AuditListHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
+	filter, err := getFilter(c)
+	if err != nil {
+		return err
+	}
+	entries, err := a.store.Query(ctx, filter)
+	if err != nil {
+		utils.ContextLogger(ctx).Error("cannot query audit entries", zap.Error(err))
+		return apierror.FromDB(err, "cannot query audit entries")
+	}
+	return c.Status(fiber.StatusOK).JSON(entries)
+}
+
+func getFilter(c *fiber.Ctx) (Filter, error) {
+	filter := Filter{
+		Principal: c.Query("principal"),
+		// Entries are scoped by cluster of route, so access to cluster is checked by auth
+		Cluster:  utils.GetRequestCluster(c),
+		Database: c.Query("database"),
+		Object:   c.Query("object"),
+		Endpoint: c.Query("endpoint"),
+		Outcome:  c.Query("outcome"),
+		Limit:    defaultLimit,
+	}
+	var err error
+	if from := c.Query("from"); len(from) > 0 {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return filter, apierror.BadRequest("cannot parse from value %s, RFC3339 is expected", from)
+		}
+	}
+	if to := c.Query("to"); len(to) > 0 {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return filter, apierror.BadRequest("cannot parse to value %s, RFC3339 is expected", to)
+		}
+	}
+	if limit := c.Query("limit"); len(limit) > 0 {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 || filter.Limit > maxLimit {
+			return filter, apierror.BadRequest("limit must be a number from 1 to %d", maxLimit)
+		}
+	}
+	return filter, nil
+}
+
+// Database and object are taken from route params or request body
+func getTarget(c *fiber.Ctx) (string, string) {
+	body := make(map[string]interface{})
+	if len(c.Body()) > 0 {
+		_ = json.Unmarshal(c.Body(), &body)
+	}
+	value := func(key string) string {
+		if param := c.Params(key); len(param) > 0 {
+			return param
+		}
+		str, _ := body[key].(string)
+		return str
+	}
+	// Requests with ambiguous database are rejected by auth, so error is not recorded
+	database, _ := utils.GetRequestDatabase(c)
+	for _, key := range objectKeys {
+		if object := value(key); len(object) > 0 {
+			return database, object
+		}
+	}
+	return database, ""
+}
+
+func getErrorStatus(err error) int {
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Status
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
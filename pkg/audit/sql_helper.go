@@ -0,0 +1,88 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+)
+
+const (
+	auditSchema       = "replication_controller"
+	auditPrepareQuery = "CREATE SCHEMA IF NOT EXISTS " + auditSchema + ";\n" +
+		"CREATE TABLE IF NOT EXISTS " + auditSchema + ".audit_log (id bigserial primary key, ts timestamptz not null, principal text not null, " +
+		"request_id text not null, method text not null, endpoint text not null, cluster text not null default '" + utils.DefaultCluster + "', database text not null, object text not null, " +
+		"dry_run boolean not null, statements text[] not null, status integer not null, outcome text not null, error text not null);\n" +
+		// Table of previous versions has no cluster column, its entries belong to default cluster
+		"ALTER TABLE " + auditSchema + ".audit_log ADD COLUMN IF NOT EXISTS cluster text not null default '" + utils.DefaultCluster + "';\n" +
+		"CREATE INDEX IF NOT EXISTS audit_log_ts_idx ON " + auditSchema + ".audit_log (ts)"
+	auditInsertQuery = "insert into " + auditSchema + ".audit_log (ts, principal, request_id, method, endpoint, cluster, database, object, dry_run, statements, status, outcome, error) " +
+		"values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)"
+	auditSelectQuery = "select ts, principal, request_id, method, endpoint, cluster, database, object, dry_run, statements, status, outcome, error from " + auditSchema + ".audit_log"
+)
+
+func getAuditPrepareQuery() string {
+	return auditPrepareQuery
+}
+
+func getAuditInsertQuery() string {
+	return auditInsertQuery
+}
+
+func getAuditSelectQuery(filter Filter) (string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if !filter.From.IsZero() {
+		addCondition("ts >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("ts < $%d", filter.To)
+	}
+	if len(filter.Principal) > 0 {
+		addCondition("principal = $%d", filter.Principal)
+	}
+	if len(filter.Cluster) > 0 {
+		addCondition("cluster = $%d", filter.Cluster)
+	}
+	if len(filter.Database) > 0 {
+		addCondition("database = $%d", filter.Database)
+	}
+	if len(filter.Object) > 0 {
+		addCondition("object = $%d", filter.Object)
+	}
+	if len(filter.Endpoint) > 0 {
+		addCondition("starts_with(endpoint, $%d)", filter.Endpoint)
+	}
+	if len(filter.Outcome) > 0 {
+		addCondition("outcome = $%d", filter.Outcome)
+	}
+
+	query := auditSelectQuery
+	if len(conditions) > 0 {
+		query = fmt.Sprintf("%s where %s", query, strings.Join(conditions, " and "))
+	}
+	query = fmt.Sprintf("%s order by ts desc, id desc", query)
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query = fmt.Sprintf("%s limit $%d", query, len(args))
+	}
+	return query, args
+}
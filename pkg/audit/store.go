@@ -0,0 +1,146 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+)
+
+// FileStore appends entries to JSONL file, each entry is synced to disk
+type FileStore struct {
+	mutex sync.Mutex
+	path  string
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open audit file %s: %w", path, err)
+	}
+	return &FileStore{path: path}, file.Close()
+}
+
+func (fs *FileStore) Append(_ context.Context, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	file, err := os.OpenFile(fs.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func (fs *FileStore) Query(_ context.Context, filter Filter) ([]Entry, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	file, err := os.Open(fs.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Warn(fmt.Sprintf("Skipping malformed audit entry in %s", fs.path))
+			continue
+		}
+		// Entries recorded before clusters were audited belong to default cluster
+		if len(entry.Cluster) == 0 {
+			entry.Cluster = utils.DefaultCluster
+		}
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.Reverse(entries)
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// TableStore keeps entries in table of controller schema in postgres,
+// the table is created on first use, so postgres is not required on start
+type TableStore struct {
+	preparer *postgres.SchemaPreparer
+}
+
+func NewTableStore(pgClient *postgres.Client) *TableStore {
+	return &TableStore{preparer: postgres.NewSchemaPreparer(pgClient, "audit table", getAuditPrepareQuery())}
+}
+
+func (ts *TableStore) Append(ctx context.Context, entry Entry) error {
+	conn, err := ts.preparer.GetConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, getAuditInsertQuery(), entry.Timestamp, entry.Principal, entry.RequestId, entry.Method,
+		entry.Endpoint, entry.Cluster, entry.Database, entry.Object, entry.DryRun, entry.Statements, entry.Status, entry.Outcome, entry.Error)
+	return err
+}
+
+func (ts *TableStore) Query(ctx context.Context, filter Filter) ([]Entry, error) {
+	conn, err := ts.preparer.GetConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	query, args := getAuditSelectQuery(filter)
+	rows, err := conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]Entry, 0)
+	for rows.Next() {
+		var entry Entry
+		err = rows.Scan(&entry.Timestamp, &entry.Principal, &entry.RequestId, &entry.Method, &entry.Endpoint,
+			&entry.Cluster, &entry.Database, &entry.Object, &entry.DryRun, &entry.Statements, &entry.Status, &entry.Outcome, &entry.Error)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
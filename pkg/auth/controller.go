@@ -0,0 +1,193 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// RoleReadOnly allows only read requests, it is implied by other roles
+	RoleReadOnly         = "read-only"
+	RolePublicationAdmin = "publication-admin"
+	RoleUserAdmin        = "user-admin"
+	// RoleClusterAdmin allows to register and remove managed clusters
+	RoleClusterAdmin = "cluster-admin"
+)
+
+var (
+	log   = utils.GetLogger()
+	roles = []string{RoleReadOnly, RolePublicationAdmin, RoleUserAdmin, RoleClusterAdmin}
+)
+
+// Principal is an API user, empty Clusters and Databases mean access to all
+// clusters and databases. Database is either name of database of default cluster
+// or cluster/database. CertSubject is a subject DN or common name of client certificate for mTLS
+type Principal struct {
+	Name         string   `json:"name"`
+	PasswordHash string   `json:"passwordHash,omitempty"`
+	CertSubject  string   `json:"certSubject,omitempty"`
+	Roles        []string `json:"roles"`
+	Clusters     []string `json:"clusters,omitempty"`
+	Databases    []string `json:"databases,omitempty"`
+}
+
+type principalsFile struct {
+	Principals []Principal `json:"principals"`
+}
+
+type Authenticator struct {
+	principals map[string]Principal
+	bySubject  map[string]Principal
+	jwt        *JWTValidator
+
+	// bcrypt is slow by design, so verified credentials are remembered by digest
+	mutex    sync.RWMutex
+	verified map[string][sha256.Size]byte
+}
+
+// LoadPrincipals reads principals from JSON file, e.g. mounted Kubernetes secret
+func LoadPrincipals(path string) ([]Principal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read principals file %s: %w", path, err)
+	}
+	var file principalsFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse principals file %s: %w", path, err)
+	}
+	return file.Principals, nil
+}
+
+// NewPrincipal creates principal with hash of plain password
+func NewPrincipal(name, password string, roles ...string) (Principal, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{Name: name, PasswordHash: string(hash), Roles: roles}, nil
+}
+
+// NewAuthenticator creates authenticator of basic auth principals,
+// bearer tokens are accepted only if jwt validator is passed
+func NewAuthenticator(principals []Principal, jwt *JWTValidator) (*Authenticator, error) {
+	if len(principals) == 0 {
+		return nil, fmt.Errorf("at least one principal must be configured")
+	}
+	byName := make(map[string]Principal, len(principals))
+	bySubject := make(map[string]Principal)
+	for _, principal := range principals {
+		err := validatePrincipal(principal)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := byName[principal.Name]; ok {
+			return nil, fmt.Errorf("principal %s is configured more than once", principal.Name)
+		}
+		byName[principal.Name] = principal
+		if len(principal.CertSubject) > 0 {
+			if _, ok := bySubject[principal.CertSubject]; ok {
+				return nil, fmt.Errorf("certificate subject %s is mapped to more than one principal", principal.CertSubject)
+			}
+			bySubject[principal.CertSubject] = principal
+		}
+		log.Info(fmt.Sprintf("API principal %s is configured with roles %s", principal.Name, strings.Join(principal.Roles, ", ")))
+	}
+	return &Authenticator{principals: byName, bySubject: bySubject, jwt: jwt, verified: make(map[string][sha256.Size]byte)}, nil
+}
+
+// Authenticate checks password of basic auth principal
+func (a *Authenticator) Authenticate(name, password string) bool {
+	principal, ok := a.principals[name]
+	if !ok || len(principal.PasswordHash) == 0 {
+		return false
+	}
+	digest := sha256.Sum256([]byte(principal.PasswordHash + password))
+	a.mutex.RLock()
+	cached, ok := a.verified[name]
+	a.mutex.RUnlock()
+	if ok && cached == digest {
+		return true
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(principal.PasswordHash), []byte(password)) != nil {
+		return false
+	}
+	a.mutex.Lock()
+	a.verified[name] = digest
+	a.mutex.Unlock()
+	return true
+}
+
+// AuthenticateCertificate maps verified client certificate to principal
+// by full subject DN first and by common name then
+func (a *Authenticator) AuthenticateCertificate(certificate *x509.Certificate) (Principal, bool) {
+	if principal, ok := a.bySubject[certificate.Subject.String()]; ok {
+		return principal, true
+	}
+	principal, ok := a.bySubject[certificate.Subject.CommonName]
+	return principal, ok && len(certificate.Subject.CommonName) > 0
+}
+
+func (p Principal) hasRole(role string) bool {
+	return role == RoleReadOnly && len(p.Roles) > 0 || slices.Contains(p.Roles, role)
+}
+
+func (p Principal) canAccessCluster(cluster string) bool {
+	return len(p.Clusters) == 0 || slices.Contains(p.Clusters, cluster)
+}
+
+// Database without cluster is a database of default cluster only
+func (p Principal) canAccess(cluster, database string) bool {
+	if !p.canAccessCluster(cluster) {
+		return false
+	}
+	if len(p.Databases) == 0 || slices.Contains(p.Databases, cluster+"/"+database) {
+		return true
+	}
+	return cluster == utils.DefaultCluster && slices.Contains(p.Databases, database)
+}
+
+func validatePrincipal(principal Principal) error {
+	if len(principal.Name) == 0 {
+		return fmt.Errorf("principal name must not be empty")
+	}
+	if len(principal.PasswordHash) == 0 && len(principal.CertSubject) == 0 {
+		return fmt.Errorf("principal %s must have password hash or certificate subject", principal.Name)
+	}
+	if _, err := bcrypt.Cost([]byte(principal.PasswordHash)); len(principal.PasswordHash) > 0 && err != nil {
+		return fmt.Errorf("password hash of principal %s is not a valid bcrypt hash", principal.Name)
+	}
+	if len(principal.Roles) == 0 {
+		return fmt.Errorf("principal %s must have at least one role", principal.Name)
+	}
+	for _, role := range principal.Roles {
+		if !slices.Contains(roles, role) {
+			return fmt.Errorf("role %s of principal %s is not supported, allowed roles: %s", role, principal.Name, strings.Join(roles, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+const principalLocal = "principal"
+
+// Middleware authenticates request with bearer token if JWT is configured,
+// otherwise or for basic credentials it falls back to basic auth principals.
+// Request without Authorization header is authenticated by verified client certificate
+func (a *Authenticator) Middleware(c *fiber.Ctx) error {
+	authorization := c.Get(fiber.HeaderAuthorization)
+	scheme, credentials, _ := strings.Cut(authorization, " ")
+	var principal Principal
+	switch {
+	case len(authorization) == 0 && hasClientCertificate(c):
+		certificate := c.Context().TLSConnectionState().VerifiedChains[0][0]
+		var ok bool
+		principal, ok = a.AuthenticateCertificate(certificate)
+		if !ok {
+			log.Warn(fmt.Sprintf("Client certificate subject %s is not mapped to principal", certificate.Subject.String()))
+			return a.unauthorized(c, "client certificate is not mapped to principal")
+		}
+	case strings.EqualFold(scheme, "Bearer") && a.jwt != nil:
+		var err error
+		principal, err = a.jwt.Validate(strings.TrimSpace(credentials))
+		if err != nil {
+			log.Warn("Bearer token is rejected", zap.Error(err))
+			return a.unauthorized(c, "bearer token is invalid")
+		}
+	case strings.EqualFold(scheme, "Basic"):
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(credentials))
+		if err != nil {
+			return a.unauthorized(c, "basic credentials are malformed")
+		}
+		name, password, _ := strings.Cut(string(decoded), ":")
+		if !a.Authenticate(name, password) {
+			return a.unauthorized(c, "username or password is invalid")
+		}
+		principal = a.principals[name]
+	default:
+		return a.unauthorized(c, "request is not authenticated")
+	}
+	c.Locals("username", principal.Name)
+	c.Locals(principalLocal, principal)
+	return c.Next()
+}
+
+// Require returns route handler checking that authenticated principal has role
+// and access to cluster and database of request. Principal limited to databases is
+// not allowed to perform requests without database, e.g. cluster wide inventory
+func (a *Authenticator) Require(role string) fiber.Handler {
+	return a.require(role, false)
+}
+
+// RequireClusterWide returns route handler for requests, which affect the whole
+// cluster regardless of their database, e.g. grant of REPLICATION attribute or
+// operations on slots by name. Only principals without database limits are allowed
+func (a *Authenticator) RequireClusterWide(role string) fiber.Handler {
+	return a.require(role, true)
+}
+
+func (a *Authenticator) require(role string, clusterWide bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := c.Locals(principalLocal).(Principal)
+		if !ok {
+			return apierror.New(fiber.StatusUnauthorized, "request is not authenticated")
+		}
+		name := principal.Name
+		if !principal.hasRole(role) {
+			return apierror.New(fiber.StatusForbidden, "principal %s doesn't have role %s", name, role)
+		}
+		cluster := utils.GetRequestCluster(c)
+		if !principal.canAccessCluster(cluster) {
+			return apierror.New(fiber.StatusForbidden, "principal %s doesn't have access to cluster %s", name, cluster)
+		}
+		database, err := utils.GetRequestDatabase(c)
+		if err != nil {
+			return apierror.BadRequest("%s", err.Error())
+		}
+		if len(principal.Databases) > 0 {
+			if clusterWide || len(database) == 0 {
+				return apierror.New(fiber.StatusForbidden, "principal %s is limited to databases and cannot perform cluster wide requests", name)
+			}
+			if !principal.canAccess(cluster, database) {
+				return apierror.New(fiber.StatusForbidden, "principal %s doesn't have access to database %s of cluster %s", name, database, cluster)
+			}
+		}
+		return c.Next()
+	}
+}
+
+func hasClientCertificate(c *fiber.Ctx) bool {
+	state := c.Context().TLSConnectionState()
+	return state != nil && len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0
+}
+
+func (a *Authenticator) unauthorized(c *fiber.Ctx, message string) error {
+	challenge := `Basic realm="Restricted"`
+	if a.jwt != nil {
+		challenge = fmt.Sprintf("Bearer realm=%q, %s", a.jwt.config.Issuer, challenge)
+	}
+	c.Set(fiber.HeaderWWWAuthenticate, challenge)
+	return apierror.New(fiber.StatusUnauthorized, "%s", message)
+}
@@ -0,0 +1,76 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequireDatabaseScope(t *testing.T) {
+	scoped := Principal{Name: "app", Roles: []string{RolePublicationAdmin}, Databases: []string{"allowed"}}
+	unscoped := Principal{Name: "admin", Roles: []string{RolePublicationAdmin}}
+	qualified := Principal{Name: "remote-app", Roles: []string{RolePublicationAdmin}, Databases: []string{"remote/allowed"}}
+	clusterScoped := Principal{Name: "remote-admin", Roles: []string{RolePublicationAdmin}, Clusters: []string{"remote"}}
+	authenticator := &Authenticator{}
+
+	cases := []struct {
+		name      string
+		principal Principal
+		handler   fiber.Handler
+		path      string
+		body      string
+		status    int
+	}{
+		{name: "body database", principal: scoped, handler: authenticator.Require(RolePublicationAdmin), path: "/", body: `{"database":"allowed"}`, status: fiber.StatusOK},
+		{name: "other body database", principal: scoped, handler: authenticator.Require(RolePublicationAdmin), path: "/", body: `{"database":"other"}`, status: fiber.StatusForbidden},
+		{name: "query differs from body", principal: scoped, handler: authenticator.Require(RolePublicationAdmin), path: "/?database=allowed", body: `{"database":"other"}`, status: fiber.StatusBadRequest},
+		{name: "query database", principal: scoped, handler: authenticator.Require(RolePublicationAdmin), path: "/?database=allowed", status: fiber.StatusOK},
+		{name: "no database", principal: scoped, handler: authenticator.Require(RolePublicationAdmin), path: "/", status: fiber.StatusForbidden},
+		{name: "cluster wide for scoped", principal: scoped, handler: authenticator.RequireClusterWide(RolePublicationAdmin), path: "/", body: `{"database":"allowed"}`, status: fiber.StatusForbidden},
+		{name: "cluster wide for unscoped", principal: unscoped, handler: authenticator.RequireClusterWide(RolePublicationAdmin), path: "/", body: `{"database":"other"}`, status: fiber.StatusOK},
+		{name: "database of other cluster", principal: scoped, handler: authenticator.Require(RolePublicationAdmin), path: "/clusters/remote", body: `{"database":"allowed"}`, status: fiber.StatusForbidden},
+		{name: "qualified database", principal: qualified, handler: authenticator.Require(RolePublicationAdmin), path: "/clusters/remote", body: `{"database":"allowed"}`, status: fiber.StatusOK},
+		{name: "qualified database of default cluster", principal: qualified, handler: authenticator.Require(RolePublicationAdmin), path: "/", body: `{"database":"allowed"}`, status: fiber.StatusForbidden},
+		{name: "allowed cluster", principal: clusterScoped, handler: authenticator.RequireClusterWide(RolePublicationAdmin), path: "/clusters/remote", status: fiber.StatusOK},
+		{name: "other cluster", principal: clusterScoped, handler: authenticator.Require(RolePublicationAdmin), path: "/", body: `{"database":"allowed"}`, status: fiber.StatusForbidden},
+	}
+	for _, tc := range cases {
+		app := fiber.New(fiber.Config{ErrorHandler: apierror.ErrorHandler})
+		principal := tc.principal
+		handlers := []fiber.Handler{func(c *fiber.Ctx) error {
+			c.Locals(principalLocal, principal)
+			return c.Next()
+		}, tc.handler, func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		}}
+		app.Post("/", handlers...)
+		app.Post("/clusters/:cluster", handlers...)
+
+		request := httptest.NewRequest(fiber.MethodPost, tc.path, strings.NewReader(tc.body))
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if response.StatusCode != tc.status {
+			t.Errorf("%s: status is %d, expected %d", tc.name, response.StatusCode, tc.status)
+		}
+	}
+}
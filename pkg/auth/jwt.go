@@ -0,0 +1,327 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	clockSkew         = time.Minute
+	jwksFetchTimeout  = 10 * time.Second
+	jwksMinRefreshGap = 30 * time.Second
+)
+
+// Only asymmetric algorithms are accepted, keys of JWKS are public
+var supportedAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// JWTConfig configures validation of bearer tokens, claims are dot separated
+// paths, e.g. realm_access.roles, roles claim is either array or space separated string
+type JWTConfig struct {
+	Issuer         string
+	Audience       string
+	JWKSFile       string
+	JWKSUrl        string
+	JWKSRefresh    time.Duration
+	SubjectClaim   string
+	RolesClaim     string
+	ClustersClaim  string
+	DatabasesClaim string
+}
+
+// JWTValidator validates tokens signed with asymmetric keys of JWKS,
+// keys are reloaded periodically and when token refers to unknown key
+type JWTValidator struct {
+	config JWTConfig
+	client *http.Client
+	parser *jwt.Parser
+
+	mutex     sync.Mutex
+	keys      map[string]crypto.PublicKey
+	loadedAt  time.Time
+	triedAt   time.Time
+	loadError error
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func NewJWTValidator(config JWTConfig) (*JWTValidator, error) {
+	if len(config.Issuer) == 0 {
+		return nil, fmt.Errorf("JWT issuer must not be empty")
+	}
+	if (len(config.JWKSFile) > 0) == (len(config.JWKSUrl) > 0) {
+		return nil, fmt.Errorf("exactly one of JWKS file and JWKS URL must be set")
+	}
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(supportedAlgs),
+		jwt.WithIssuer(config.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+	}
+	if len(config.Audience) > 0 {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+	validator := &JWTValidator{config: config, client: &http.Client{Timeout: jwksFetchTimeout}, parser: jwt.NewParser(options...)}
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+	err := validator.loadKeys()
+	if err != nil && len(config.JWKSFile) > 0 {
+		return nil, err
+	}
+	if err != nil {
+		// Identity provider may be unavailable on start, keys are loaded on first token
+		log.Warn(fmt.Sprintf("JWKS cannot be loaded from %s: %s", config.JWKSUrl, err.Error()))
+	}
+	return validator, nil
+}
+
+// Validate checks signature and registered claims of token and maps its claims to principal
+func (v *JWTValidator) Validate(token string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, v.verificationKeys)
+	if err != nil {
+		return Principal{}, err
+	}
+	return v.toPrincipal(claims)
+}
+
+// Token without kid is verified by any of keys
+func (v *JWTValidator) verificationKeys(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	keys, err := v.getKeys(kid)
+	if err != nil {
+		return nil, err
+	}
+	keySet := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(keys))}
+	for _, key := range keys {
+		keySet.Keys = append(keySet.Keys, key)
+	}
+	return keySet, nil
+}
+
+// Roles of claim unknown to controller are ignored. Token without databases
+// is not scoped to all databases unless it has cluster admin role
+func (v *JWTValidator) toPrincipal(claims jwt.MapClaims) (Principal, error) {
+	subject, _ := getClaim(claims, v.config.SubjectClaim).(string)
+	if len(subject) == 0 {
+		return Principal{}, fmt.Errorf("token doesn't have %s claim", v.config.SubjectClaim)
+	}
+	principal := Principal{
+		Name:      subject,
+		Clusters:  getStrings(claims, v.config.ClustersClaim),
+		Databases: getStrings(claims, v.config.DatabasesClaim),
+	}
+	for _, role := range getStrings(claims, v.config.RolesClaim) {
+		if slices.Contains(roles, role) && !slices.Contains(principal.Roles, role) {
+			principal.Roles = append(principal.Roles, role)
+		}
+	}
+	if len(principal.Roles) == 0 {
+		return Principal{}, fmt.Errorf("token of %s doesn't have any of controller roles", subject)
+	}
+	if len(principal.Databases) == 0 && !slices.Contains(principal.Roles, RoleClusterAdmin) {
+		return Principal{}, fmt.Errorf("token of %s doesn't have %s claim and role %s", subject, v.config.DatabasesClaim, RoleClusterAdmin)
+	}
+	return principal, nil
+}
+
+func (v *JWTValidator) getKeys(kid string) ([]crypto.PublicKey, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	_, known := v.keys[kid]
+	expired := v.config.JWKSRefresh > 0 && time.Since(v.loadedAt) > v.config.JWKSRefresh
+	if (expired || (len(kid) > 0 && !known)) && time.Since(v.triedAt) > jwksMinRefreshGap {
+		if err := v.loadKeys(); err != nil {
+			log.Warn(fmt.Sprintf("JWKS cannot be reloaded: %s", err.Error()))
+		}
+	}
+	if len(v.keys) == 0 {
+		return nil, fmt.Errorf("JWKS is not loaded: %w", v.loadError)
+	}
+	if len(kid) > 0 {
+		key, ok := v.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("token key %s is unknown", kid)
+		}
+		return []crypto.PublicKey{key}, nil
+	}
+	keys := make([]crypto.PublicKey, 0, len(v.keys))
+	for _, key := range v.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Must be called under mutex, previous keys are kept if load fails
+func (v *JWTValidator) loadKeys() error {
+	v.triedAt = time.Now()
+	data, err := v.readJWKS()
+	if err == nil {
+		var keys map[string]crypto.PublicKey
+		keys, err = parseJWKS(data)
+		if err == nil {
+			v.keys = keys
+			v.loadedAt = v.triedAt
+			log.Info(fmt.Sprintf("JWKS has been loaded with %d keys", len(keys)))
+		}
+	}
+	v.loadError = err
+	return err
+}
+
+func (v *JWTValidator) readJWKS() ([]byte, error) {
+	if len(v.config.JWKSFile) > 0 {
+		return os.ReadFile(v.config.JWKSFile)
+	}
+	response, err := v.client.Get(v.config.JWKSUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS request to %s returned status %d", v.config.JWKSUrl, response.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(response.Body, 1024*1024))
+}
+
+// Keys are indexed by kid, keys without kid get their position as index
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("cannot parse JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey)
+	for i, jwk := range jwks.Keys {
+		if len(jwk.Use) > 0 && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Warn(fmt.Sprintf("JWKS key %s is skipped: %s", jwk.Kid, err.Error()))
+			continue
+		}
+		kid := jwk.Kid
+		if len(kid) == 0 {
+			kid = fmt.Sprintf("#%d", i)
+		}
+		keys[kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS doesn't contain signature keys")
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curve %s is not supported", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("key type %s is not supported", k.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func getClaim(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+func getStrings(claims map[string]interface{}, path string) []string {
+	switch value := getClaim(claims, path).(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWTValidator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator := newTestValidator(t, key)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":       "https://issuer",
+			"aud":       "controller",
+			"sub":       "deployer",
+			"exp":       time.Now().Add(time.Hour).Unix(),
+			"roles":     []string{RolePublicationAdmin},
+			"databases": []string{"db1"},
+		}
+	}
+	cases := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+		method jwt.SigningMethod
+		valid  bool
+	}{
+		{name: "scoped token", valid: true},
+		{name: "cluster admin without databases", modify: func(c jwt.MapClaims) {
+			c["roles"] = []string{RoleClusterAdmin}
+			delete(c, "databases")
+		}, valid: true},
+		{name: "missing databases", modify: func(c jwt.MapClaims) { delete(c, "databases") }},
+		{name: "empty databases", modify: func(c jwt.MapClaims) { c["databases"] = []string{} }},
+		{name: "expired", modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "missing expiration", modify: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "other issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://other" }},
+		{name: "other audience", modify: func(c jwt.MapClaims) { c["aud"] = "other" }},
+		{name: "unknown roles", modify: func(c jwt.MapClaims) { c["roles"] = []string{"admin"} }},
+		{name: "symmetric algorithm", method: jwt.SigningMethodHS256},
+	}
+	for _, tc := range cases {
+		claims := valid()
+		if tc.modify != nil {
+			tc.modify(claims)
+		}
+		var signingKey interface{} = key
+		method := tc.method
+		if method == nil {
+			method = jwt.SigningMethodRS256
+		} else {
+			signingKey = []byte("secret")
+		}
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = "test"
+		signed, err := token.SignedString(signingKey)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		principal, err := validator.Validate(signed)
+		if tc.valid && err != nil {
+			t.Errorf("%s: token is rejected: %v", tc.name, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("%s: token is accepted as %+v", tc.name, principal)
+		}
+	}
+}
+
+func newTestValidator(t *testing.T, key *rsa.PrivateKey) *JWTValidator {
+	encode := func(value []byte) string {
+		return base64.RawURLEncoding.EncodeToString(value)
+	}
+	jwks, err := json.Marshal(map[string][]jsonWebKey{"keys": {{
+		Kty: "RSA",
+		Kid: "test",
+		Use: "sig",
+		N:   encode(key.N.Bytes()),
+		E:   encode(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(path, jwks, 0600); err != nil {
+		t.Fatal(err)
+	}
+	validator, err := NewJWTValidator(JWTConfig{
+		Issuer:         "https://issuer",
+		Audience:       "controller",
+		JWKSFile:       path,
+		SubjectClaim:   "sub",
+		RolesClaim:     "roles",
+		DatabasesClaim: "databases",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return validator
+}
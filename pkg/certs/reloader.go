@@ -0,0 +1,147 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"go.uber.org/zap"
+)
+
+var log = utils.GetLogger()
+
+// Reloader serves server certificate and client CA bundle from files
+// and reloads them on change, so rotated certificates are applied without restart
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	interval     time.Duration
+
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+	modTimes    map[string]time.Time
+}
+
+// NewReloader loads files on creation, client CA is optional
+// and client certificates are not requested without it
+func NewReloader(certFile, keyFile, clientCAFile string, interval time.Duration) (*Reloader, error) {
+	reloader := &Reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		interval:     interval,
+		modTimes:     make(map[string]time.Time),
+	}
+	if _, err := reloader.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Run checks files for changes until ctx is done, failed reload keeps previous certificates
+func (r *Reloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.reloadIfChanged()
+			if err != nil {
+				log.Error("TLS certificates cannot be reloaded, previous ones are used", zap.Error(err))
+			} else if reloaded {
+				log.Info("TLS certificates have been reloaded")
+			}
+		}
+	}
+}
+
+// TLSConfig returns server config, which reads current certificates on each handshake.
+// If clientCertRequired is false, client certificate is verified only if presented
+func (r *Reloader) TLSConfig(clientCertRequired bool) *tls.Config {
+	clientAuth := tls.NoClientCert
+	if len(r.clientCAFile) > 0 {
+		clientAuth = tls.VerifyClientCertIfGiven
+		if clientCertRequired {
+			clientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mutex.RLock()
+			defer r.mutex.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.certificate},
+				ClientAuth:   clientAuth,
+				ClientCAs:    r.clientCAs,
+			}, nil
+		},
+	}
+}
+
+func (r *Reloader) reloadIfChanged() (bool, error) {
+	files := []string{r.certFile, r.keyFile}
+	if len(r.clientCAFile) > 0 {
+		files = append(files, r.clientCAFile)
+	}
+	modTimes := make(map[string]time.Time, len(files))
+	changed := false
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		modTimes[file] = info.ModTime()
+		changed = changed || !info.ModTime().Equal(r.modTimes[file])
+	}
+	if !changed {
+		return false, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("cannot load server certificate %s: %w", r.certFile, err)
+	}
+	var clientCAs *x509.CertPool
+	if len(r.clientCAFile) > 0 {
+		data, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return false, fmt.Errorf("cannot read client CA bundle %s: %w", r.clientCAFile, err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data) {
+			return false, fmt.Errorf("client CA bundle %s doesn't contain certificates", r.clientCAFile)
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.certificate = &certificate
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	return true, nil
+}
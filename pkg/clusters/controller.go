@@ -0,0 +1,364 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/diagnostics"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/identity"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+	publication "github.com/Netcracker/pgskipper-replication-controller/pkg/publicaion"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/slots"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/subscriptions"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/users"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+)
+
+const (
+	// DefaultCluster is configured with controller flags and serves routes without cluster id
+	DefaultCluster = utils.DefaultCluster
+
+	SourceFlags = "flags"
+	SourceFile  = "file"
+	SourceAPI   = "api"
+
+	defaultPort     = 5432
+	defaultUser     = "postgres"
+	defaultDatabase = "postgres"
+)
+
+var (
+	log = utils.GetLogger()
+
+	clusterIdRegexp = regexp.MustCompile("^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$")
+)
+
+// ClusterConfig is connection configuration of registered cluster,
+// Host may contain comma separated hosts like POSTGRES_HOST
+type ClusterConfig struct {
+	Id          string   `json:"id"`
+	Host        string   `json:"host"`
+	Port        int      `json:"port,omitempty"`
+	User        string   `json:"user,omitempty"`
+	Password    string   `json:"password,omitempty"`
+	SSLMode     string   `json:"sslMode,omitempty"`
+	SSLRootCert string   `json:"sslRootCert,omitempty"`
+	SSLCert     string   `json:"sslCert,omitempty"`
+	SSLKey      string   `json:"sslKey,omitempty"`
+	PatroniUrls []string `json:"patroniUrls,omitempty"`
+}
+
+// ClusterInfo is cluster configuration without credentials
+type ClusterInfo struct {
+	Id          string   `json:"id"`
+	Host        string   `json:"host"`
+	Port        int      `json:"port"`
+	User        string   `json:"user"`
+	SSLMode     string   `json:"sslMode"`
+	PatroniUrls []string `json:"patroniUrls,omitempty"`
+	Source      string   `json:"source"`
+	Health      string   `json:"health,omitempty"`
+}
+
+type clustersFile struct {
+	Clusters []ClusterConfig `json:"clusters"`
+}
+
+// Cluster keeps postgres clients and controllers of one cluster,
+// subscriptions are managed with subscriber client
+type Cluster struct {
+	info ClusterInfo
+	// config is stored to state file for clusters registered with API
+	config    ClusterConfig
+	client    *postgres.Client
+	subClient *postgres.Client
+
+	publications  *publication.PublicationController
+	users         *users.UsersController
+	slots         *slots.SlotController
+	subscriptions *subscriptions.SubscriptionController
+	identity      *identity.IdentityController
+	diagnostics   *diagnostics.DiagnosticsController
+}
+
+// Registry keeps clusters by id, default cluster cannot be removed.
+// Clusters registered with API are kept only in memory unless state file is set
+type Registry struct {
+	mutex     sync.RWMutex
+	clusters  map[string]*Cluster
+	statePath string
+
+	resolverTTL     time.Duration
+	resolverTimeout time.Duration
+}
+
+// LoadClusters reads JSON file of form {"clusters": [...]}
+func LoadClusters(path string) ([]ClusterConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read clusters file %s: %w", path, err)
+	}
+	var file clustersFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse clusters file %s: %w", path, err)
+	}
+	return file.Clusters, nil
+}
+
+// SetStateFile loads clusters registered with API before restart and stores
+// them to the file on each change, the file is created on first registration
+func (r *Registry) SetStateFile(path string) error {
+	configs, err := LoadClusters(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for _, config := range configs {
+		if err = r.Add(config, SourceAPI); err != nil {
+			return fmt.Errorf("cluster %s of state file cannot be registered: %w", config.Id, err)
+		}
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.statePath = path
+	log.Info(fmt.Sprintf("Clusters registered with API are stored to %s", path))
+	return nil
+}
+
+// NewDefaultCluster wraps clients created from controller flags
+func NewDefaultCluster(client *postgres.Client, subClient *postgres.Client, patroniUrls []string) *Cluster {
+	info := ClusterInfo{
+		Id:          DefaultCluster,
+		Host:        client.GetHost(),
+		Port:        client.GetPort(),
+		User:        client.GetUser(),
+		SSLMode:     client.TLS.Mode,
+		PatroniUrls: patroniUrls,
+		Source:      SourceFlags,
+	}
+	return newCluster(info, client, subClient)
+}
+
+// NewRegistry creates registry with default cluster, resolver settings are used
+// for clusters with Patroni URLs
+func NewRegistry(defaultCluster *Cluster, resolverTTL, resolverTimeout time.Duration) *Registry {
+	return &Registry{
+		clusters:        map[string]*Cluster{DefaultCluster: defaultCluster},
+		resolverTTL:     resolverTTL,
+		resolverTimeout: resolverTimeout,
+	}
+}
+
+func newCluster(info ClusterInfo, client *postgres.Client, subClient *postgres.Client) *Cluster {
+	return &Cluster{
+		info:          info,
+		client:        client,
+		subClient:     subClient,
+		publications:  publication.NewPublicationController(client),
+		users:         users.NewUsersController(client),
+		slots:         slots.NewSlotController(client),
+		subscriptions: subscriptions.NewSubscriptionController(subClient),
+		identity:      identity.NewIdentityController(client),
+		diagnostics:   diagnostics.NewDiagnosticsController(client),
+	}
+}
+
+// Add validates config and registers cluster, connection is not required
+// on registration and is tracked by cluster health
+func (r *Registry) Add(config ClusterConfig, source string) error {
+	err := validateConfig(&config)
+	if err != nil {
+		return err
+	}
+	tlsConfig := postgres.NewTLSConfig("", config.SSLMode, config.SSLRootCert, config.SSLCert, config.SSLKey)
+	if err = tlsConfig.Validate(); err != nil {
+		return apierror.BadRequest("TLS configuration of cluster %s is invalid: %s", config.Id, err.Error())
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.clusters[config.Id]; ok {
+		return apierror.Conflict("cluster %s already exists", config.Id)
+	}
+	client := postgres.NewClient(config.Host, config.Port, config.User, config.Password, defaultDatabase, tlsConfig)
+	if len(config.PatroniUrls) > 0 {
+		client.SetResolver(postgres.NewPatroniResolver(config.PatroniUrls, r.resolverTTL, r.resolverTimeout))
+	}
+	info := ClusterInfo{
+		Id:          config.Id,
+		Host:        config.Host,
+		Port:        config.Port,
+		User:        config.User,
+		SSLMode:     tlsConfig.Mode,
+		PatroniUrls: config.PatroniUrls,
+		Source:      source,
+	}
+	cluster := newCluster(info, client, client)
+	cluster.config = config
+	if source == SourceAPI {
+		if err = r.saveState(cluster, ""); err != nil {
+			client.Close()
+			return err
+		}
+	}
+	r.clusters[config.Id] = cluster
+	log.Info(fmt.Sprintf("Cluster %s has been registered for host=%s port=%d from %s", config.Id, config.Host, config.Port, source))
+	return nil
+}
+
+// Remove unregisters cluster and closes its connection pools
+func (r *Registry) Remove(id string) error {
+	if id == DefaultCluster {
+		return apierror.BadRequest("default cluster cannot be removed")
+	}
+	r.mutex.Lock()
+	cluster, ok := r.clusters[id]
+	if !ok {
+		r.mutex.Unlock()
+		return apierror.NotFound("cluster %s doesn't exist", id)
+	}
+	if cluster.info.Source == SourceAPI {
+		if err := r.saveState(nil, id); err != nil {
+			r.mutex.Unlock()
+			return err
+		}
+	}
+	delete(r.clusters, id)
+	r.mutex.Unlock()
+	cluster.client.Close()
+	log.Info(fmt.Sprintf("Cluster %s has been removed", id))
+	return nil
+}
+
+func (r *Registry) Get(id string) (*Cluster, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	cluster, ok := r.clusters[id]
+	if !ok {
+		return nil, apierror.NotFound("cluster %s doesn't exist", id)
+	}
+	return cluster, nil
+}
+
+func (r *Registry) List() []ClusterInfo {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	infos := make([]ClusterInfo, 0, len(r.clusters))
+	for _, cluster := range r.clusters {
+		infos = append(infos, cluster.info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Id < infos[j].Id
+	})
+	return infos
+}
+
+func (r *Registry) isPersistent() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.statePath) > 0
+}
+
+// saveState writes clusters registered with API along with added and without removed one,
+// must be called under lock before registry is changed. File is replaced atomically
+func (r *Registry) saveState(added *Cluster, removed string) error {
+	if len(r.statePath) == 0 {
+		return nil
+	}
+	configs := make([]ClusterConfig, 0, len(r.clusters)+1)
+	for id, cluster := range r.clusters {
+		if cluster.info.Source == SourceAPI && id != removed {
+			configs = append(configs, cluster.config)
+		}
+	}
+	if added != nil {
+		configs = append(configs, added.config)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Id < configs[j].Id
+	})
+	data, err := json.MarshalIndent(clustersFile{Clusters: configs}, "", "  ")
+	if err == nil {
+		err = writeFileAtomically(r.statePath, data)
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("Clusters cannot be stored to %s: %s", r.statePath, err.Error()))
+		return fmt.Errorf("clusters cannot be stored: %w", err)
+	}
+	return nil
+}
+
+// Credentials of clusters are stored, so file is readable only by controller
+func writeFileAtomically(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(temp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Info returns cluster configuration with current health of postgres
+func (cl *Cluster) Info(ctx context.Context) ClusterInfo {
+	info := cl.info
+	info.Health = postgres.HealthUP
+	if err := cl.client.CheckHealth(ctx); err != nil {
+		utils.ContextLogger(ctx).Warn(fmt.Sprintf("Cluster %s is unavailable: %s", info.Id, err.Error()))
+		info.Health = postgres.HealthOOS
+	}
+	return info
+}
+
+func validateConfig(config *ClusterConfig) error {
+	if !clusterIdRegexp.MatchString(config.Id) {
+		return apierror.BadRequest("cluster id may contain only lower case letters, numbers and hyphen, up to 63 characters")
+	}
+	if config.Id == DefaultCluster {
+		return apierror.BadRequest("cluster id %s is reserved", DefaultCluster)
+	}
+	if len(strings.TrimSpace(config.Host)) == 0 {
+		return apierror.BadRequest("host of cluster %s must not be empty", config.Id)
+	}
+	if config.Port == 0 {
+		config.Port = defaultPort
+	}
+	if len(config.User) == 0 {
+		config.User = defaultUser
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+)
+
+func newTestRegistry() *Registry {
+	client := postgres.NewClient("localhost", defaultPort, defaultUser, "", defaultDatabase, postgres.TLSConfig{})
+	return NewRegistry(NewDefaultCluster(client, client, nil), time.Minute, time.Second)
+}
+
+func TestApiClustersSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clusters.json")
+	registry := newTestRegistry()
+	if err := registry.SetStateFile(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, config := range []ClusterConfig{{Id: "remote", Host: "pg-remote", Password: "secret"}, {Id: "other", Host: "pg-other"}} {
+		if err := registry.Add(config, SourceAPI); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := registry.Add(ClusterConfig{Id: "static", Host: "pg-static"}, SourceFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Remove("other"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("state file is not readable only by owner: %v", err)
+	}
+
+	restarted := newTestRegistry()
+	if err := restarted.SetStateFile(path); err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0)
+	for _, info := range restarted.List() {
+		ids = append(ids, info.Id)
+	}
+	if len(ids) != 2 || ids[0] != DefaultCluster || ids[1] != "remote" {
+		t.Errorf("clusters after restart are %v", ids)
+	}
+	cluster, err := restarted.Get("remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cluster.info.Source != SourceAPI || cluster.config.Password != "secret" {
+		t.Errorf("cluster is restored as %+v", cluster.info)
+	}
+}
+
+func TestApiClustersWithoutStateFile(t *testing.T) {
+	registry := newTestRegistry()
+	if err := registry.Add(ClusterConfig{Id: "remote", Host: "pg-remote"}, SourceAPI); err != nil {
+		t.Fatal(err)
+	}
+	if registry.isPersistent() {
+		t.Error("registry without state file is persistent")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusters
+
+import (
+	"fmt"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/diagnostics"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/identity"
+	publication "github.com/Netcracker/pgskipper-replication-controller/pkg/publicaion"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/slots"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/subscriptions"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/users"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+const clusterParam = "cluster"
+
+func (r *Registry) ClusterListHandler(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(r.List())
+}
+
+func (r *Registry) ClusterGetHandler(c *fiber.Ctx) error {
+	cluster, err := r.Get(c.Params(clusterParam))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(cluster.Info(utils.GetRequestContext(c)))
+}
+
+// Clusters registered with API are not written to clusters file, they survive
+// restart only if state file is configured, otherwise they are kept in memory
+func (r *Registry) ClusterCreateHandler(c *fiber.Ctx) error {
+	var config ClusterConfig
+	err := c.BodyParser(&config)
+	if err != nil {
+		return apierror.BadRequest("cannot parse request: %s", err.Error())
+	}
+	err = r.Add(config, SourceAPI)
+	if err != nil {
+		return err
+	}
+	if !r.isPersistent() {
+		log.Warn(fmt.Sprintf("Cluster %s is kept only in memory and is lost on restart, configure clusters state file to keep it", config.Id))
+	}
+	return c.Status(fiber.StatusOK).SendString("OK")
+}
+
+func (r *Registry) ClusterDeleteHandler(c *fiber.Ctx) error {
+	err := r.Remove(c.Params(clusterParam))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).SendString("OK")
+}
+
+// Publications returns handler, which calls controller method of cluster from route,
+// default cluster is used for routes without cluster id
+func (r *Registry) Publications(handler func(*publication.PublicationController, *fiber.Ctx) error) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cluster, err := r.getRequestCluster(c)
+		if err != nil {
+			return err
+		}
+		return handler(cluster.publications, c)
+	}
+}
+
+func (r *Registry) Users(handler func(*users.UsersController, *fiber.Ctx) error) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cluster, err := r.getRequestCluster(c)
+		if err != nil {
+			return err
+		}
+		return handler(cluster.users, c)
+	}
+}
+
+func (r *Registry) Slots(handler func(*slots.SlotController, *fiber.Ctx) error) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cluster, err := r.getRequestCluster(c)
+		if err != nil {
+			return err
+		}
+		return handler(cluster.slots, c)
+	}
+}
+
+func (r *Registry) Subscriptions(handler func(*subscriptions.SubscriptionController, *fiber.Ctx) error) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cluster, err := r.getRequestCluster(c)
+		if err != nil {
+			return err
+		}
+		return handler(cluster.subscriptions, c)
+	}
+}
+
+func (r *Registry) Identity(handler func(*identity.IdentityController, *fiber.Ctx) error) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cluster, err := r.getRequestCluster(c)
+		if err != nil {
+			return err
+		}
+		return handler(cluster.identity, c)
+	}
+}
+
+func (r *Registry) Diagnostics(handler func(*diagnostics.DiagnosticsController, *fiber.Ctx) error) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cluster, err := r.getRequestCluster(c)
+		if err != nil {
+			return err
+		}
+		return handler(cluster.diagnostics, c)
+	}
+}
+
+func (r *Registry) getRequestCluster(c *fiber.Ctx) (*Cluster, error) {
+	return r.Get(utils.GetRequestCluster(c))
+}
@@ -0,0 +1,291 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+
+	maxReportedTables = 20
+)
+
+var (
+	replicationUser = utils.GetEnv("REPLICATION_USER", "")
+)
+
+type DiagnosticsController struct {
+	pgClient *postgres.Client
+}
+
+type Report struct {
+	Database string  `json:"database"`
+	Status   string  `json:"status"`
+	Checks   []Check `json:"checks"`
+}
+
+type Check struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Hint    string   `json:"hint,omitempty"`
+	Objects []string `json:"objects,omitempty"`
+}
+
+func NewDiagnosticsController(pgClient *postgres.Client) *DiagnosticsController {
+	return &DiagnosticsController{pgClient: pgClient}
+}
+
+// RunPreflight checks readiness of database for logical replication,
+// replication user checks are skipped if username is empty
+func (dc *DiagnosticsController) RunPreflight(ctx context.Context, database, username string) (Report, error) {
+	log := utils.ContextLogger(ctx)
+
+	if len(database) == 0 {
+		err := apierror.BadRequest("database must not be empty")
+		log.Error(err.Error(), zap.Error(err))
+		return Report{}, err
+	}
+	if len(username) == 0 {
+		username = replicationUser
+	}
+
+	log.Info(fmt.Sprintf("Replication readiness checks started for database %s", database))
+	conn, err := dc.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return Report{}, apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	checks, err := checkSettings(ctx, conn)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot check replication settings for database %s", database), zap.Error(err))
+		return Report{}, apierror.FromDB(err, "cannot check replication settings for database %s", database)
+	}
+	identityCheck, err := checkReplicaIdentity(ctx, conn)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot check replica identity of tables for database %s", database), zap.Error(err))
+		return Report{}, apierror.FromDB(err, "cannot check replica identity of tables for database %s", database)
+	}
+	unloggedCheck, err := checkUnloggedTables(ctx, conn)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot check unlogged tables for database %s", database), zap.Error(err))
+		return Report{}, apierror.FromDB(err, "cannot check unlogged tables for database %s", database)
+	}
+	checks = append(checks, identityCheck, unloggedCheck)
+
+	userChecks, err := checkReplicationUser(ctx, conn, username)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot check replication user %s for database %s", username, database), zap.Error(err))
+		return Report{}, apierror.FromDB(err, "cannot check replication user %s for database %s", username, database)
+	}
+	checks = append(checks, userChecks...)
+
+	report := Report{Database: database, Status: StatusPass, Checks: checks}
+	for _, check := range checks {
+		report.Status = worstStatus(report.Status, check.Status)
+	}
+	log.Info(fmt.Sprintf("Replication readiness checks finished for database %s with status %s", database, report.Status))
+	return report, nil
+}
+
+func checkSettings(ctx context.Context, conn postgres.Conn) ([]Check, error) {
+	var walLevel string
+	var maxSlots, usedSlots, maxSenders, usedSenders int
+	err := conn.QueryRow(ctx, getSettingsQuery()).Scan(&walLevel, &maxSlots, &usedSlots, &maxSenders, &usedSenders)
+	if err != nil {
+		return nil, err
+	}
+
+	walCheck := Check{Name: "walLevel", Status: StatusPass, Message: fmt.Sprintf("wal_level is %s", walLevel)}
+	if walLevel != "logical" {
+		walCheck.Status = StatusFail
+		walCheck.Hint = "set wal_level = logical and restart postgres"
+	}
+	slotsCheck := capacityCheck("replicationSlots", "max_replication_slots", maxSlots, usedSlots,
+		"increase max_replication_slots and restart postgres or drop unused replication slots")
+	sendersCheck := capacityCheck("walSenders", "max_wal_senders", maxSenders, usedSenders,
+		"increase max_wal_senders and restart postgres")
+	return []Check{walCheck, slotsCheck, sendersCheck}, nil
+}
+
+// Last free slot or sender is reported as warning, because it is consumed by new subscription
+func capacityCheck(name, setting string, max, used int, hint string) Check {
+	free := max - used
+	check := Check{Name: name, Status: StatusPass, Message: fmt.Sprintf("%d of %d %s are free", free, max, setting)}
+	if free <= 0 {
+		check.Status = StatusFail
+		check.Hint = hint
+	} else if free == 1 {
+		check.Status = StatusWarn
+		check.Hint = hint
+	}
+	return check
+}
+
+func checkReplicaIdentity(ctx context.Context, conn postgres.Conn) (Check, error) {
+	tables, err := queryTables(ctx, conn, getNoIdentityTablesQuery())
+	if err != nil {
+		return Check{}, err
+	}
+	check := Check{Name: "replicaIdentity", Status: StatusPass, Message: "all tables have primary key or replica identity"}
+	if len(tables) > 0 {
+		check.Status = StatusWarn
+		check.Message = fmt.Sprintf("%d tables have neither primary key nor replica identity, UPDATE and DELETE on them fail once published", len(tables))
+		check.Hint = "add primary key or set REPLICA IDENTITY USING INDEX or FULL for listed tables"
+		check.Objects = limitObjects(tables)
+	}
+	return check, nil
+}
+
+func checkUnloggedTables(ctx context.Context, conn postgres.Conn) (Check, error) {
+	tables, err := queryTables(ctx, conn, getUnloggedTablesQuery())
+	if err != nil {
+		return Check{}, err
+	}
+	check := Check{Name: "unloggedTables", Status: StatusPass, Message: "there are no unlogged tables"}
+	if len(tables) > 0 {
+		check.Status = StatusWarn
+		check.Message = fmt.Sprintf("%d unlogged tables are not replicated", len(tables))
+		check.Hint = "execute ALTER TABLE ... SET LOGGED for tables which must be replicated"
+		check.Objects = limitObjects(tables)
+	}
+	return check, nil
+}
+
+func checkReplicationUser(ctx context.Context, conn postgres.Conn, username string) ([]Check, error) {
+	if len(username) == 0 {
+		return []Check{{
+			Name:    "replicationUser",
+			Status:  StatusWarn,
+			Message: "replication user is not specified, privileges are not checked",
+			Hint:    "pass user query parameter or set REPLICATION_USER env",
+		}}, nil
+	}
+
+	rows, err := conn.Query(ctx, getReplicationUserQuery(), username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return []Check{{
+			Name:    "replicationUser",
+			Status:  StatusFail,
+			Message: fmt.Sprintf("user %s doesn't exist", username),
+			Hint:    fmt.Sprintf("create user %s with LOGIN and REPLICATION attributes", username),
+		}}, rows.Err()
+	}
+	var replication, login, connect bool
+	err = rows.Scan(&replication, &login, &connect)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	userCheck := Check{Name: "replicationUser", Status: StatusPass, Message: fmt.Sprintf("user %s can login with REPLICATION attribute", username)}
+	if !login {
+		userCheck.Status = StatusFail
+		userCheck.Message = fmt.Sprintf("user %s cannot login", username)
+		userCheck.Hint = fmt.Sprintf("execute ALTER ROLE %s WITH LOGIN", username)
+	} else if !replication {
+		userCheck.Status = StatusFail
+		userCheck.Message = fmt.Sprintf("user %s has no REPLICATION attribute", username)
+		userCheck.Hint = "grant replication to user with POST /users/grant"
+	}
+
+	connectCheck := Check{Name: "databaseConnect", Status: StatusPass, Message: fmt.Sprintf("user %s can connect to database", username)}
+	if !connect {
+		connectCheck.Status = StatusFail
+		connectCheck.Message = fmt.Sprintf("user %s has no CONNECT privilege on database", username)
+		connectCheck.Hint = fmt.Sprintf("execute GRANT CONNECT ON DATABASE ... TO %s", username)
+	}
+
+	tables, err := queryTables(ctx, conn, getNotSelectableTablesQuery(), username)
+	if err != nil {
+		return nil, err
+	}
+	selectCheck := Check{Name: "tablePrivileges", Status: StatusPass, Message: fmt.Sprintf("user %s can select all tables", username)}
+	if len(tables) > 0 {
+		selectCheck.Status = StatusWarn
+		selectCheck.Message = fmt.Sprintf("user %s cannot select %d tables, initial copy of them fails", username, len(tables))
+		selectCheck.Hint = fmt.Sprintf("grant SELECT on published tables to %s", username)
+		selectCheck.Objects = limitObjects(tables)
+	}
+	return []Check{userCheck, connectCheck, selectCheck}, nil
+}
+
+func queryTables(ctx context.Context, conn postgres.Conn, query string, args ...interface{}) ([]string, error) {
+	rows, err := conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make([]string, 0)
+	for rows.Next() {
+		var table string
+		err = rows.Scan(&table)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+func limitObjects(objects []string) []string {
+	if len(objects) <= maxReportedTables {
+		return objects
+	}
+	return append(objects[:maxReportedTables], fmt.Sprintf("... and %d more", len(objects)-maxReportedTables))
+}
+
+func worstStatus(first, second string) string {
+	if first == StatusFail || second == StatusFail {
+		return StatusFail
+	}
+	if first == StatusWarn || second == StatusWarn {
+		return StatusWarn
+	}
+	return StatusPass
+}
+
+// LogReport writes not passed checks as warnings, used for advisory runs
+func LogReport(ctx context.Context, report Report) {
+	log := utils.ContextLogger(ctx)
+	for _, check := range report.Checks {
+		if check.Status == StatusPass {
+			continue
+		}
+		message := fmt.Sprintf("Replication readiness check %s for database %s: %s: %s", check.Name, report.Database, check.Status, check.Message)
+		if len(check.Objects) > 0 {
+			message = fmt.Sprintf("%s [%s]", message, strings.Join(check.Objects, ", "))
+		}
+		log.Warn(message, zap.String("hint", check.Hint))
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnostics
+
+import (
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (dc *DiagnosticsController) DiagnosticsHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
+	report, err := dc.RunPreflight(ctx, c.Params("database"), c.Query("user"))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
@@ -0,0 +1,43 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnostics
+
+const (
+	settingsQuery            = "select current_setting('wal_level'), current_setting('max_replication_slots')::int, (select count(*) from pg_replication_slots)::int, current_setting('max_wal_senders')::int, (select count(*) from pg_stat_activity where backend_type = 'walsender')::int"
+	noIdentityTablesQuery    = "select n.nspname || '.' || c.relname from pg_class c join pg_namespace n on n.oid = c.relnamespace where c.relkind in ('r', 'p') and not c.relispartition and n.nspname not in ('pg_catalog', 'information_schema') and n.nspname not like 'pg_toast%' and (c.relreplident = 'n' or (c.relreplident = 'd' and not exists(select 1 from pg_index i where i.indrelid = c.oid and i.indisprimary))) order by 1"
+	unloggedTablesQuery      = "select n.nspname || '.' || c.relname from pg_class c join pg_namespace n on n.oid = c.relnamespace where c.relkind in ('r', 'p') and c.relpersistence = 'u' and n.nspname not in ('pg_catalog', 'information_schema') order by 1"
+	replicationUserQuery     = "select rolreplication or rolsuper, rolcanlogin, has_database_privilege(rolname, current_database(), 'CONNECT') from pg_roles where rolname = $1"
+	notSelectableTablesQuery = "select n.nspname || '.' || c.relname from pg_class c join pg_namespace n on n.oid = c.relnamespace where c.relkind in ('r', 'p') and n.nspname not in ('pg_catalog', 'information_schema') and n.nspname not like 'pg_toast%' and not has_table_privilege($1, c.oid, 'SELECT') order by 1"
+)
+
+func getSettingsQuery() string {
+	return settingsQuery
+}
+
+func getNoIdentityTablesQuery() string {
+	return noIdentityTablesQuery
+}
+
+func getUnloggedTablesQuery() string {
+	return unloggedTablesQuery
+}
+
+func getReplicationUserQuery() string {
+	return replicationUserQuery
+}
+
+func getNotSelectableTablesQuery() string {
+	return notSelectableTablesQuery
+}
@@ -0,0 +1,167 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	StatusUp      = "UP"
+	StatusDown    = "OUT_OF_SERVICE"
+	StatusUnknown = "UNKNOWN"
+)
+
+var log = utils.GetLogger()
+
+type CheckFunc func(ctx context.Context) error
+
+type Component struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Latency   string    `json:"latency,omitempty"`
+	LastCheck time.Time `json:"lastCheck,omitempty"`
+}
+
+type Status struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// Checker runs registered checks in background and caches their results,
+// so health requests never wait for unavailable dependencies
+type Checker struct {
+	mutex      sync.RWMutex
+	interval   time.Duration
+	timeout    time.Duration
+	checks     map[string]CheckFunc
+	components map[string]Component
+}
+
+func NewChecker(interval, timeout time.Duration) *Checker {
+	return &Checker{
+		interval:   interval,
+		timeout:    timeout,
+		checks:     make(map[string]CheckFunc),
+		components: make(map[string]Component),
+	}
+}
+
+func (hc *Checker) Register(name string, check CheckFunc) {
+	hc.mutex.Lock()
+	defer hc.mutex.Unlock()
+	hc.checks[name] = check
+	hc.components[name] = Component{Status: StatusUnknown}
+}
+
+func (hc *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(hc.interval)
+	defer ticker.Stop()
+	for {
+		hc.checkAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status is UP only if all components are UP
+func (hc *Checker) Status() Status {
+	hc.mutex.RLock()
+	defer hc.mutex.RUnlock()
+
+	status := Status{Status: StatusUp, Components: make(map[string]Component, len(hc.components))}
+	for name, component := range hc.components {
+		status.Components[name] = component
+		if component.Status != StatusUp {
+			status.Status = StatusDown
+		}
+	}
+	return status
+}
+
+func (hc *Checker) checkAll(ctx context.Context) {
+	hc.mutex.RLock()
+	names := make([]string, 0, len(hc.checks))
+	for name := range hc.checks {
+		names = append(names, name)
+	}
+	hc.mutex.RUnlock()
+	sort.Strings(names)
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			hc.check(ctx, name)
+		}(name)
+	}
+	wg.Wait()
+}
+
+func (hc *Checker) check(ctx context.Context, name string) {
+	hc.mutex.RLock()
+	check := hc.checks[name]
+	previous := hc.components[name]
+	hc.mutex.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := runCheck(ctx, check)
+	component := Component{Status: StatusUp, Latency: time.Since(start).String(), LastCheck: start}
+	if err != nil {
+		component.Status = StatusDown
+		component.Error = err.Error()
+	}
+	if component.Status != previous.Status {
+		log.Info(fmt.Sprintf("Health of %s changed from %s to %s", name, previous.Status, component.Status), zap.Error(err))
+	}
+
+	hc.mutex.Lock()
+	hc.components[name] = component
+	hc.mutex.Unlock()
+}
+
+// Check is executed in separate goroutine, so hung check doesn't exceed timeout
+func runCheck(ctx context.Context, check CheckFunc) error {
+	ch := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				ch <- fmt.Errorf("health check panicked: %v", r)
+			}
+		}()
+		ch <- check(ctx)
+	}()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("health check timeout expired")
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// LiveHandler reports that controller process is able to serve requests,
+// it doesn't depend on availability of postgres
+func (hc *Checker) LiveHandler(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(Status{Status: StatusUp})
+}
+
+// ReadyHandler reports cached status of all components
+func (hc *Checker) ReadyHandler(c *fiber.Ctx) error {
+	status := hc.Status()
+	if status.Status != StatusUp {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(status)
+	}
+	return c.Status(fiber.StatusOK).JSON(status)
+}
@@ -0,0 +1,252 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/audit"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	IdentityDefault = "default"
+	IdentityFull    = "full"
+	IdentityIndex   = "index"
+	IdentityNothing = "nothing"
+
+	// Modes of replica identity check for tables added to publication
+	ModeIgnore = "ignore"
+	ModeRefuse = "refuse"
+	ModeFix    = "fix"
+)
+
+var (
+	identities = []string{IdentityDefault, IdentityFull, IdentityIndex, IdentityNothing}
+	modes      = []string{ModeIgnore, ModeRefuse, ModeFix}
+)
+
+type IdentityController struct {
+	pgClient *postgres.Client
+}
+
+type TableIdentity struct {
+	Schema        string `json:"schema"`
+	Name          string `json:"name"`
+	Identity      string `json:"identity"`
+	Index         string `json:"index,omitempty"`
+	HasPrimaryKey bool   `json:"hasPrimaryKey"`
+	// Safe is false if UPDATE and DELETE fail on table once it is published
+	Safe bool `json:"safe"`
+}
+
+func (t TableIdentity) String() string {
+	return fmt.Sprintf("%s.%s", t.Schema, t.Name)
+}
+
+func NewIdentityController(pgClient *postgres.Client) *IdentityController {
+	return &IdentityController{pgClient: pgClient}
+}
+
+func (ic *IdentityController) listIdentities(ctx context.Context, database, schema string) ([]TableIdentity, error) {
+	log := utils.ContextLogger(ctx)
+
+	if len(database) == 0 {
+		err := apierror.BadRequest("database must not be empty")
+		log.Error(err.Error(), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info(fmt.Sprintf("List replica identity of tables for database %s", database))
+	conn, err := ic.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return nil, apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	rows, err := conn.Query(ctx, getIdentityListQuery(), schema)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot list replica identity of tables for database %s", database))
+		return nil, apierror.FromDB(err, "cannot list replica identity of tables for database %s", database)
+	}
+	defer rows.Close()
+
+	tables := make([]TableIdentity, 0)
+	for rows.Next() {
+		table, err := scanIdentity(rows)
+		if err != nil {
+			log.Error(fmt.Sprintf("cannot scan replica identity of tables for database %s", database))
+			return nil, apierror.FromDB(err, "cannot scan replica identity of tables for database %s", database)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// Absent table is reported as apierror with 404 status
+func (ic *IdentityController) getIdentity(ctx context.Context, database, table string) (TableIdentity, error) {
+	log := utils.ContextLogger(ctx)
+
+	if len(database) == 0 || len(table) == 0 {
+		err := apierror.BadRequest("database and table must not be empty")
+		log.Error(err.Error(), zap.Error(err))
+		return TableIdentity{}, err
+	}
+
+	log.Info(fmt.Sprintf("Get replica identity of table %s for database %s", table, database))
+	conn, err := ic.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return TableIdentity{}, apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	return getIdentityInternal(ctx, conn, database, table)
+}
+
+func getIdentityInternal(ctx context.Context, conn postgres.Conn, database, table string) (TableIdentity, error) {
+	log := utils.ContextLogger(ctx)
+
+	rows, err := conn.Query(ctx, getIdentityGetQuery(), table)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot get replica identity of table %s for database %s", table, database))
+		return TableIdentity{}, apierror.FromDB(err, "cannot get replica identity of table %s for database %s", table, database)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return TableIdentity{}, apierror.NotFound("table %s doesn't exist in database %s", table, database)
+	}
+	identity, err := scanIdentity(rows)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot scan replica identity of table %s for database %s", table, database))
+		return TableIdentity{}, apierror.FromDB(err, "cannot scan replica identity of table %s for database %s", table, database)
+	}
+	return identity, nil
+}
+
+func (ic *IdentityController) setIdentity(ctx context.Context, request IdentityRequest) error {
+	log := utils.ContextLogger(ctx)
+
+	err := validateSetRequest(request)
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return err
+	}
+	database := request.Database
+	table := quoteTable(request.Schema, request.Table)
+
+	log.Info(fmt.Sprintf("Replica identity %s set started for table %s in database %s", request.Identity, table, database))
+	conn, err := ic.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	current, err := getIdentityInternal(ctx, conn, database, table)
+	if err != nil {
+		return err
+	}
+	if current.Identity == request.Identity && current.Index == request.Index {
+		log.Info(fmt.Sprintf("Table %s already has replica identity %s in database %s", table, request.Identity, database))
+		return nil
+	}
+
+	query := getIdentitySetQuery(current.Schema, current.Name, request.Identity, request.Index)
+	log.Debug(query)
+	audit.AddStatement(ctx, query)
+	_, err = conn.Exec(ctx, query)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot set replica identity of table %s for database %s", table, database), zap.Error(err))
+		return apierror.FromDB(err, "cannot set replica identity of table %s for database %s", table, database)
+	}
+	if request.Identity == IdentityNothing {
+		log.Warn(fmt.Sprintf("UPDATE and DELETE of table %s fail once it is published", table))
+	}
+	log.Info(fmt.Sprintf("Replica identity %s has been set for table %s in database %s", request.Identity, table, database))
+	return nil
+}
+
+// FindUnsafeTables returns tables among requested ones, for which UPDATE and DELETE fail
+// once they are published. Tables are names resolvable by to_regclass, all tables
+// of database are checked if neither tables nor schemas are requested
+func FindUnsafeTables(ctx context.Context, conn postgres.Conn, tables []string, schemas []string) ([]TableIdentity, error) {
+	allTables := len(tables) == 0 && len(schemas) == 0
+	rows, err := conn.Query(ctx, getUnsafeTablesQuery(), tables, schemas, allTables)
+	if err != nil {
+		return nil, apierror.FromDB(err, "cannot check replica identity of tables")
+	}
+	defer rows.Close()
+
+	unsafe := make([]TableIdentity, 0)
+	for rows.Next() {
+		table := TableIdentity{Identity: IdentityDefault}
+		err = rows.Scan(&table.Schema, &table.Name)
+		if err != nil {
+			return nil, apierror.FromDB(err, "cannot scan replica identity of tables")
+		}
+		unsafe = append(unsafe, table)
+	}
+	return unsafe, nil
+}
+
+// GetFixQueries returns queries setting FULL replica identity for tables
+func GetFixQueries(tables []TableIdentity) []string {
+	queries := make([]string, 0, len(tables))
+	for _, table := range tables {
+		queries = append(queries, getIdentitySetQuery(table.Schema, table.Name, IdentityFull, ""))
+	}
+	return queries
+}
+
+func ValidateMode(mode string) error {
+	if len(mode) > 0 && !slices.Contains(modes, mode) {
+		return apierror.BadRequest("replica identity mode %s is not supported, allowed modes: %s", mode, strings.Join(modes, ", "))
+	}
+	return nil
+}
+
+func scanIdentity(rows pgx.Rows) (TableIdentity, error) {
+	var table TableIdentity
+	err := rows.Scan(&table.Schema, &table.Name, &table.Identity, &table.Index, &table.HasPrimaryKey)
+	table.Safe = table.Identity == IdentityFull || table.Identity == IdentityIndex ||
+		(table.Identity == IdentityDefault && table.HasPrimaryKey)
+	return table, err
+}
+
+func validateSetRequest(request IdentityRequest) error {
+	if len(request.Database) == 0 {
+		return apierror.BadRequest("database must not be empty")
+	}
+	if len(request.Table) == 0 {
+		return apierror.BadRequest("table must not be empty")
+	}
+	if !slices.Contains(identities, request.Identity) {
+		return apierror.BadRequest("replica identity %s is not supported, allowed values: %s", request.Identity, strings.Join(identities, ", "))
+	}
+	if request.Identity == IdentityIndex && len(request.Index) == 0 {
+		return apierror.BadRequest("index must not be empty for replica identity %s", IdentityIndex)
+	}
+	if request.Identity != IdentityIndex && len(request.Index) > 0 {
+		return apierror.BadRequest("index is allowed only for replica identity %s", IdentityIndex)
+	}
+	return nil
+}
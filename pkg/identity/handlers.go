@@ -0,0 +1,79 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"strings"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type IdentityRequest struct {
+	Database string `json:"database"`
+	Schema   string `json:"schema,omitempty"`
+	Table    string `json:"table"`
+	Identity string `json:"identity"`
+	Index    string `json:"index,omitempty"`
+}
+
+func (ic *IdentityController) IdentityListHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
+	tables, err := ic.listIdentities(ctx, c.Params("database"), c.Query("schema"))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(tables)
+}
+
+// Table is passed as "schema.table", search_path is used if schema is omitted
+func (ic *IdentityController) IdentityGetHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
+	table, err := ic.getIdentity(ctx, c.Params("database"), c.Params("table"))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(table)
+}
+
+func (ic *IdentityController) IdentitySetHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
+	request, err := getIdentityReq(c)
+	if err != nil {
+		return err
+	}
+	err = ic.setIdentity(ctx, request)
+	if err != nil {
+		return err
+	}
+	return ok(c)
+}
+
+func getIdentityReq(c *fiber.Ctx) (IdentityRequest, error) {
+	var request IdentityRequest
+	if len(c.Body()) > 0 {
+		err := c.BodyParser(&request)
+		if err != nil {
+			return request, apierror.BadRequest("cannot parse request: %s", err.Error())
+		}
+	}
+	request.Identity = strings.ToLower(request.Identity)
+	return request, nil
+}
+
+func ok(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).SendString("OK")
+}
@@ -0,0 +1,59 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	identityColumns    = "select n.nspname, c.relname, case c.relreplident when 'd' then 'default' when 'f' then 'full' when 'i' then 'index' else 'nothing' end, coalesce(ic.relname, ''), exists(select 1 from pg_index pk where pk.indrelid = c.oid and pk.indisprimary) from pg_class c join pg_namespace n on n.oid = c.relnamespace left join pg_index i on i.indrelid = c.oid and i.indisreplident left join pg_class ic on ic.oid = i.indexrelid"
+	identityUserTables = "c.relkind in ('r', 'p') and n.nspname not in ('pg_catalog', 'information_schema') and n.nspname not like 'pg_toast%'"
+	identityListQuery  = identityColumns + " where " + identityUserTables + " and ($1 = '' or n.nspname = $1) order by 1, 2"
+	identityGetQuery   = identityColumns + " where " + identityUserTables + " and c.oid = to_regclass($1)"
+	unsafeTablesQuery  = "select n.nspname, c.relname from pg_class c join pg_namespace n on n.oid = c.relnamespace where " + identityUserTables + " and (c.relreplident = 'n' or (c.relreplident = 'd' and not exists(select 1 from pg_index pk where pk.indrelid = c.oid and pk.indisprimary))) and ($3 or c.oid in (select to_regclass(t) from unnest($1::text[]) t) or n.nspname = any($2::text[])) order by 1, 2"
+	identitySetQuery   = "ALTER TABLE %s REPLICA IDENTITY %s"
+	identityUsingIndex = "USING INDEX %s"
+)
+
+func getIdentityListQuery() string {
+	return identityListQuery
+}
+
+func getIdentityGetQuery() string {
+	return identityGetQuery
+}
+
+func getUnsafeTablesQuery() string {
+	return unsafeTablesQuery
+}
+
+func getIdentitySetQuery(schema, table, identity, index string) string {
+	clause := strings.ToUpper(identity)
+	if identity == IdentityIndex {
+		clause = fmt.Sprintf(identityUsingIndex, pgx.Identifier{index}.Sanitize())
+	}
+	return fmt.Sprintf(identitySetQuery, quoteTable(schema, table), clause)
+}
+
+func quoteTable(schema, table string) string {
+	if len(schema) == 0 {
+		return pgx.Identifier{table}.Sanitize()
+	}
+	return pgx.Identifier{schema, table}.Sanitize()
+}
@@ -17,12 +17,15 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/zap"
 
 	"github.com/jackc/pgconn"
@@ -33,6 +36,17 @@ const (
 	HealthOOS = "OUT_OF_SERVICE"
 
 	healthQuery = "SELECT 1 FROM pg_catalog.pg_tables"
+
+	serverVersionQuery = "select current_setting('server_version_num')::int"
+
+	slotsLagQuery = "select slot_name, slot_type, coalesce(database, ''), active, active_pid, coalesce(wal_status, ''), safe_wal_size, " +
+		"coalesce(pg_wal_lsn_diff(pg_current_wal_lsn(), restart_lsn), 0)::bigint, pg_wal_lsn_diff(pg_current_wal_lsn(), confirmed_flush_lsn)::bigint, %s " +
+		"from pg_replication_slots order by slot_name"
+	inactiveSinceColumn = "inactive_since"
+	inactiveSinceAbsent = "null::timestamptz"
+
+	// PG17 reports when slot became inactive
+	inactiveSinceVersion = 170000
 )
 
 var (
@@ -45,6 +59,14 @@ type Conn interface {
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
 	Close(ctx context.Context) error
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	// ExecStatements executes each statement as exactly one command with
+	// extended protocol, several statements are executed in a single transaction
+	ExecStatements(ctx context.Context, statements ...string) error
+	// PrepareStatement parses and analyzes statement on server without execution
+	PrepareStatement(ctx context.Context, sql string) error
+	// InTx executes fn in transaction, which is committed if fn succeeds and rolled back otherwise.
+	// Statements of transaction are not retried on failover
+	InTx(ctx context.Context, fn func(Conn) error) error
 }
 
 type ClusterAdapter interface {
@@ -57,17 +79,37 @@ type ClusterAdapter interface {
 	GetPort() int
 }
 
+type SlotLag struct {
+	Name             string `json:"slotName"`
+	Type             string `json:"slotType"`
+	Database         string `json:"database,omitempty"`
+	Active           bool   `json:"active"`
+	ActivePid        *int32 `json:"activePid,omitempty"`
+	WalStatus        string `json:"walStatus"`
+	SafeWalSize      *int64 `json:"safeWalSize,omitempty"`
+	RetainedWalBytes int64  `json:"retainedWalBytes"`
+	LagBytes         *int64 `json:"lagBytes,omitempty"`
+	ExceedsThreshold bool   `json:"exceedsThreshold"`
+	// InactiveSince is reported by PG17 and later
+	InactiveSince *time.Time `json:"inactiveSince,omitempty"`
+}
+
+// Client connects to the first writable of comma separated hosts,
+// port is used for hosts without own port. IPv6 literals of hosts are enclosed in brackets, e.g. [::1] or [::1]:5432
 type Client struct {
 	Host      string
 	Port      int
-	SSl       string
+	TLS       TLSConfig
 	User      string
 	Password  string
 	DefaultDB string
-	Health    string
+
+	hosts    []string
+	resolver HostResolver
+	pools    *poolCache
 }
 
-func NewClient(host string, port int, username, password string, database string, ssl string) *Client {
+func NewClient(host string, port int, username, password string, database string, tlsConfig TLSConfig) *Client {
 	username = url.PathEscape(username)
 	password = url.PathEscape(password)
 
@@ -76,33 +118,26 @@ func NewClient(host string, port int, username, password string, database string
 		Port:      port,
 		User:      username,
 		Password:  password,
-		SSl:       ssl,
-		Health:    HealthUP,
+		TLS:       tlsConfig,
 		DefaultDB: database,
+		hosts:     parseHosts(host, port),
+		pools:     newPoolCache(),
 	}
-	log.Debug(fmt.Sprintf("Checking connection for host=%s port=%d with database %s", host, port, database))
-	c.RequestHealth()
-	log.Info("PG client has been initialized")
+	// Availability of postgres is not required on start, it is tracked by health checker
+	log.Info(fmt.Sprintf("PG client has been initialized for host=%s port=%d with database %s and sslmode %s", host, port, database, tlsConfig.Mode))
 	return c
 }
 
-func (ca Client) RequestHealth() string {
-	ch := make(chan string, 1)
-	go func() {
-		ch <- ca.getHealth()
-	}()
-
-	select {
-	case healthStatus := <-ch:
-		if healthStatus == HealthOOS {
-			panic(fmt.Errorf("postgres is unavailable"))
-		}
-		ca.Health = healthStatus
-	case <-time.After(connTimeout * time.Second):
-		panic("postgres connection timeout expired")
-	}
+// SetResolver makes client connect to resolved primary,
+// configured hosts are used if primary cannot be resolved
+func (ca *Client) SetResolver(resolver HostResolver) {
+	ca.resolver = resolver
+	ca.pools.onInvalidate = resolver.Invalidate
+}
 
-	return ca.Health
+// CheckHealth executes health query within connection timeout
+func (ca Client) CheckHealth(ctx context.Context) error {
+	return ca.executeHealthQuery(ctx)
 }
 
 func (ca Client) GetPort() int {
@@ -137,34 +172,77 @@ func (ca Client) GetConnectionToDbWithUser(ctx context.Context, database string,
 }
 
 func (ca Client) getConnectionToDbWithUser(ctx context.Context, database string, username string, password string) (Conn, error) {
-	conn, err := pgx.Connect(ctx, ca.getConnectionUrl(username, password, database))
+	acquire := func(ctx context.Context) (*pgxpool.Conn, error) {
+		hosts := ca.getHosts(ctx)
+		key := poolKey{hosts: hosts, database: database, username: username, password: password}
+		return ca.pools.acquire(ctx, key, ca.getConnectionUrl(hosts, username, password, database))
+	}
+	var conn *pgxpool.Conn
+	// Primary is resolved again after invalidation, so connection is established to new primary.
+	// No statement of request is executed on acquire, so it is always retried
+	isAcquire := func(error) bool { return true }
+	err := withRetry(ctx, ca.pools.invalidate, func(context.Context) error { return nil }, isAcquire, func() error {
+		var err error
+		conn, err = acquire(ctx)
+		return err
+	})
 	if err != nil {
 		log.Error("Error occurred during connect to DB", zap.Error(err))
+		if ca.resolver != nil {
+			ca.resolver.Invalidate()
+		}
 		return nil, err
 	}
-	return conn, nil
+	return &pooledConn{Conn: conn, invalidate: ca.pools.invalidate, reacquire: acquire}, nil
+}
+
+// PoolStats returns statistics of cached connection pools
+func (ca Client) PoolStats() []PoolStat {
+	return ca.pools.stats()
 }
 
-func (ca Client) getConnectionUrl(username string, password string, database string) string {
-	if ca.SSl == "on" {
-		return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?%s", username, password, ca.Host, ca.GetPort(), database, "sslmode=require")
-	} else {
-		return fmt.Sprintf("postgres://%s:%s@%s:%d/%s", username, password, ca.Host, ca.GetPort(), database)
+// Close closes all cached connection pools
+func (ca Client) Close() {
+	ca.pools.close()
+}
+
+func (ca Client) getHosts(ctx context.Context) string {
+	if ca.resolver != nil {
+		leader, err := ca.resolver.Resolve(ctx)
+		if err == nil {
+			return leader
+		}
+		log.Warn("Primary cannot be resolved, configured hosts are used", zap.Error(err))
 	}
+	return strings.Join(ca.hosts, ",")
 }
 
-func (ca Client) getHealth() string {
-	err := ca.executeHealthQuery()
-	if err != nil {
-		log.Error("Postgres is unavailable", zap.Error(err))
-		return HealthOOS
-	} else {
-		return HealthUP
+func (ca Client) getConnectionUrl(hosts string, username string, password string, database string) string {
+	params := ca.TLS.urlParams()
+	if strings.Contains(hosts, ",") {
+		// pg_is_in_recovery is checked on each new connection to find primary
+		params += "&target_session_attrs=primary"
+	}
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?%s", username, password, hosts, database, params)
+}
+
+func parseHosts(hosts string, port int) []string {
+	result := make([]string, 0)
+	for _, host := range strings.Split(hosts, ",") {
+		host = strings.TrimSpace(host)
+		if len(host) == 0 {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(port))
+		}
+		result = append(result, host)
 	}
+	return result
 }
 
-func (ca Client) executeHealthQuery() error {
-	ctx, cancel := context.WithTimeout(context.Background(), connTimeout*time.Second)
+func (ca Client) executeHealthQuery(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, connTimeout*time.Second)
 	defer cancel()
 
 	conn, err := ca.getConnectionToDbWithUser(ctx, ca.DefaultDB, ca.User, ca.Password)
@@ -177,6 +255,57 @@ func (ca Client) executeHealthQuery() error {
 	return err
 }
 
+// Slot exceeds threshold if either retained WAL or lag is not less than thresholdBytes,
+// threshold not greater than zero is disabled
+func (ca Client) RequestSlotsLag(ctx context.Context, thresholdBytes int64) ([]SlotLag, error) {
+	conn, err := ca.GetConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	version, err := GetServerVersion(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	inactiveSince := inactiveSinceAbsent
+	if version >= inactiveSinceVersion {
+		inactiveSince = inactiveSinceColumn
+	}
+	rows, err := conn.Query(ctx, fmt.Sprintf(slotsLagQuery, inactiveSince))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := make([]SlotLag, 0)
+	for rows.Next() {
+		var slot SlotLag
+		err = rows.Scan(&slot.Name, &slot.Type, &slot.Database, &slot.Active, &slot.ActivePid, &slot.WalStatus,
+			&slot.SafeWalSize, &slot.RetainedWalBytes, &slot.LagBytes, &slot.InactiveSince)
+		if err != nil {
+			return nil, err
+		}
+		slot.ExceedsThreshold = slot.exceeds(thresholdBytes)
+		slots = append(slots, slot)
+	}
+	return slots, rows.Err()
+}
+
+func (slot SlotLag) exceeds(thresholdBytes int64) bool {
+	if thresholdBytes <= 0 {
+		return false
+	}
+	return slot.RetainedWalBytes >= thresholdBytes || (slot.LagBytes != nil && *slot.LagBytes >= thresholdBytes)
+}
+
+// GetServerVersion returns server_version_num, e.g. 160002
+func GetServerVersion(ctx context.Context, conn Conn) (int, error) {
+	var version int
+	err := conn.QueryRow(ctx, serverVersionQuery).Scan(&version)
+	return version, err
+}
+
 func EscapeInputValue(value string) string {
 	singleQuote := strings.ReplaceAll(value, "'", "''")
 	return strings.ReplaceAll(singleQuote, "\"", "\"\"")
@@ -0,0 +1,40 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import "testing"
+
+func TestSlotLagExceedsThreshold(t *testing.T) {
+	lag := int64(100)
+	slot := SlotLag{RetainedWalBytes: 50, LagBytes: &lag}
+	cases := []struct {
+		threshold int64
+		exceeds   bool
+	}{
+		{threshold: -1, exceeds: false},
+		{threshold: 0, exceeds: false},
+		{threshold: 50, exceeds: true},
+		{threshold: 100, exceeds: true},
+		{threshold: 101, exceeds: false},
+	}
+	for _, tc := range cases {
+		if got := slot.exceeds(tc.threshold); got != tc.exceeds {
+			t.Errorf("threshold %d: expected exceeds %v, got %v", tc.threshold, tc.exceeds, got)
+		}
+	}
+	if (SlotLag{}).exceeds(0) {
+		t.Error("empty slot exceeds disabled threshold")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	patroniClusterPath = "/cluster"
+	patroniLeaderRole  = "leader"
+)
+
+// HostResolver returns host:port of current primary, Invalidate
+// is called on connection errors to resolve primary again
+type HostResolver interface {
+	Resolve(ctx context.Context) (string, error)
+	Invalidate()
+}
+
+type patroniCluster struct {
+	Members []patroniMember `json:"members"`
+}
+
+type patroniMember struct {
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+	State string `json:"state"`
+	Host  string `json:"host"`
+	Port  int    `json:"port"`
+}
+
+// PatroniResolver finds leader with Patroni REST API, urls are
+// requested in order until one of them responds. Leader is cached for ttl
+type PatroniResolver struct {
+	urls   []string
+	ttl    time.Duration
+	client *http.Client
+
+	mutex      sync.Mutex
+	leader     string
+	resolvedAt time.Time
+	// generation is changed by Invalidate, so leader requested before invalidation is not cached
+	generation int
+}
+
+func NewPatroniResolver(urls []string, ttl time.Duration, timeout time.Duration) *PatroniResolver {
+	return &PatroniResolver{
+		urls:   urls,
+		ttl:    ttl,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Resolve requests Patroni without lock, so slow response doesn't block resolution of cached leader
+func (pr *PatroniResolver) Resolve(ctx context.Context) (string, error) {
+	if len(pr.urls) == 0 {
+		return "", fmt.Errorf("leader cannot be resolved with Patroni: no Patroni urls are configured")
+	}
+	pr.mutex.Lock()
+	if len(pr.leader) > 0 && time.Since(pr.resolvedAt) < pr.ttl {
+		defer pr.mutex.Unlock()
+		return pr.leader, nil
+	}
+	generation := pr.generation
+	pr.mutex.Unlock()
+
+	var err error
+	for _, url := range pr.urls {
+		var leader string
+		leader, err = pr.requestLeader(ctx, url)
+		if err != nil {
+			log.Warn(fmt.Sprintf("Cannot get leader from Patroni %s", url), zap.Error(err))
+			continue
+		}
+		pr.setLeader(leader, generation)
+		return leader, nil
+	}
+	return "", fmt.Errorf("leader cannot be resolved with Patroni: %w", err)
+}
+
+func (pr *PatroniResolver) setLeader(leader string, generation int) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	if generation != pr.generation {
+		return
+	}
+	if leader != pr.leader {
+		log.Info(fmt.Sprintf("Patroni leader has been resolved to %s", leader))
+	}
+	pr.leader = leader
+	pr.resolvedAt = time.Now()
+}
+
+func (pr *PatroniResolver) Invalidate() {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.resolvedAt = time.Time{}
+	pr.generation++
+}
+
+func (pr *PatroniResolver) requestLeader(ctx context.Context, url string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(url, "/")+patroniClusterPath, nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := pr.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+
+	var cluster patroniCluster
+	err = json.NewDecoder(response.Body).Decode(&cluster)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse cluster state: %w", err)
+	}
+	for _, member := range cluster.Members {
+		if member.Role == patroniLeaderRole && member.State == "running" && len(member.Host) > 0 {
+			return net.JoinHostPort(member.Host, strconv.Itoa(member.Port)), nil
+		}
+	}
+	return "", fmt.Errorf("running leader is not found among %d members", len(cluster.Members))
+}
@@ -0,0 +1,183 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type patroniStub struct {
+	mutex    sync.Mutex
+	status   int
+	members  []patroniMember
+	requests atomic.Int32
+}
+
+func (ps *patroniStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ps.requests.Add(1)
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	if r.URL.Path != patroniClusterPath {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if ps.status != http.StatusOK {
+		w.WriteHeader(ps.status)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(patroniCluster{Members: ps.members})
+}
+
+func (ps *patroniStub) set(status int, members ...patroniMember) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	ps.status = status
+	ps.members = members
+}
+
+func newPatroniStub(t *testing.T, status int, members ...patroniMember) (*patroniStub, string) {
+	stub := &patroniStub{status: status, members: members}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return stub, server.URL
+}
+
+func TestPatroniResolverLeader(t *testing.T) {
+	_, url := newPatroniStub(t, http.StatusOK,
+		patroniMember{Name: "pg-1", Role: "replica", State: "streaming", Host: "10.0.0.1", Port: 5432},
+		patroniMember{Name: "pg-2", Role: "leader", State: "running", Host: "10.0.0.2", Port: 5433})
+
+	leader, err := NewPatroniResolver([]string{url}, time.Minute, time.Second).Resolve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leader != "10.0.0.2:5433" {
+		t.Errorf("leader is resolved to %s", leader)
+	}
+}
+
+func TestPatroniResolverNoLeader(t *testing.T) {
+	_, url := newPatroniStub(t, http.StatusOK,
+		patroniMember{Name: "pg-1", Role: "replica", State: "streaming", Host: "10.0.0.1", Port: 5432},
+		patroniMember{Name: "pg-2", Role: "leader", State: "stopped", Host: "10.0.0.2", Port: 5432})
+
+	leader, err := NewPatroniResolver([]string{url}, time.Minute, time.Second).Resolve(context.Background())
+	if err == nil {
+		t.Errorf("leader is resolved to %s without running leader", leader)
+	}
+}
+
+func TestPatroniResolverHttpError(t *testing.T) {
+	_, failed := newPatroniStub(t, http.StatusServiceUnavailable)
+	_, available := newPatroniStub(t, http.StatusOK,
+		patroniMember{Name: "pg-1", Role: "leader", State: "running", Host: "10.0.0.1", Port: 5432})
+
+	leader, err := NewPatroniResolver([]string{failed}, time.Minute, time.Second).Resolve(context.Background())
+	if err == nil {
+		t.Errorf("leader is resolved to %s with unavailable Patroni", leader)
+	}
+	leader, err = NewPatroniResolver([]string{failed, available}, time.Minute, time.Second).Resolve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leader != "10.0.0.1:5432" {
+		t.Errorf("leader is resolved to %s", leader)
+	}
+}
+
+func TestPatroniResolverWithoutUrls(t *testing.T) {
+	leader, err := NewPatroniResolver(nil, time.Minute, time.Second).Resolve(context.Background())
+	if err == nil {
+		t.Fatalf("leader is resolved to %s without Patroni urls", leader)
+	}
+	if errors.Unwrap(err) != nil {
+		t.Errorf("error wraps nil cause: %v", err)
+	}
+}
+
+func TestPatroniResolverInvalidate(t *testing.T) {
+	stub, url := newPatroniStub(t, http.StatusOK,
+		patroniMember{Name: "pg-1", Role: "leader", State: "running", Host: "10.0.0.1", Port: 5432})
+	resolver := NewPatroniResolver([]string{url}, time.Minute, time.Second)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if leader, err := resolver.Resolve(ctx); err != nil || leader != "10.0.0.1:5432" {
+			t.Fatalf("leader is resolved to %s: %v", leader, err)
+		}
+	}
+	if requests := stub.requests.Load(); requests != 1 {
+		t.Errorf("Patroni is requested %d times within ttl", requests)
+	}
+
+	stub.set(http.StatusOK,
+		patroniMember{Name: "pg-1", Role: "replica", State: "streaming", Host: "10.0.0.1", Port: 5432},
+		patroniMember{Name: "pg-2", Role: "leader", State: "running", Host: "10.0.0.2", Port: 5432})
+	if leader, _ := resolver.Resolve(ctx); leader != "10.0.0.1:5432" {
+		t.Errorf("cached leader is changed to %s before invalidation", leader)
+	}
+	resolver.Invalidate()
+	if leader, err := resolver.Resolve(ctx); err != nil || leader != "10.0.0.2:5432" {
+		t.Errorf("leader is resolved to %s after invalidation: %v", leader, err)
+	}
+	if requests := stub.requests.Load(); requests != 2 {
+		t.Errorf("Patroni is requested %d times, expected 2", requests)
+	}
+}
+
+func TestPatroniResolverRequestsWithoutLock(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_ = json.NewEncoder(w).Encode(patroniCluster{Members: []patroniMember{{Role: "leader", State: "running", Host: "10.0.0.1", Port: 5432}}})
+	}))
+	t.Cleanup(server.Close)
+	resolver := NewPatroniResolver([]string{server.URL}, time.Minute, 5*time.Second)
+
+	resolved := make(chan string)
+	go func() {
+		leader, _ := resolver.Resolve(context.Background())
+		resolved <- leader
+	}()
+	invalidated := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		resolver.Invalidate()
+		close(invalidated)
+	}()
+	select {
+	case <-invalidated:
+	case <-time.After(time.Second):
+		t.Error("Invalidate is blocked by request to Patroni")
+	}
+	close(release)
+	if leader := <-resolved; leader != "10.0.0.1:5432" {
+		t.Errorf("leader is resolved to %s", leader)
+	}
+	// Leader requested before invalidation is not cached
+	resolver.mutex.Lock()
+	defer resolver.mutex.Unlock()
+	if len(resolver.leader) > 0 {
+		t.Errorf("leader %s requested before invalidation is cached", resolver.leader)
+	}
+}
@@ -0,0 +1,503 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+const (
+	readOnlyTransaction = "25006"
+	adminShutdown       = "57P01"
+	crashShutdown       = "57P02"
+	cannotConnectNow    = "57P03"
+)
+
+var (
+	poolMaxConns        = utils.GetEnvInt("PG_POOL_MAX_CONNS", 4)
+	poolMaxPools        = utils.GetEnvInt("PG_POOL_MAX_POOLS", 50)
+	poolIdleTimeout     = time.Duration(utils.GetEnvInt("PG_POOL_IDLE_TIMEOUT_SEC", 300)) * time.Second
+	poolMaxConnLifetime = time.Duration(utils.GetEnvInt("PG_POOL_MAX_CONN_LIFETIME_SEC", 1800)) * time.Second
+)
+
+type PoolStat struct {
+	Hosts           string    `json:"hosts"`
+	Database        string    `json:"database"`
+	User            string    `json:"user"`
+	MaxConns        int32     `json:"maxConns"`
+	TotalConns      int32     `json:"totalConns"`
+	AcquiredConns   int32     `json:"acquiredConns"`
+	IdleConns       int32     `json:"idleConns"`
+	AcquireCount    int64     `json:"acquireCount"`
+	NewConnsCount   int64     `json:"newConnsCount"`
+	AcquireDuration string    `json:"acquireDuration"`
+	LastUsed        time.Time `json:"lastUsed"`
+}
+
+// pooledConn returns connection to the pool on Close and invalidates
+// all pools if error shows that connection is lost or server is not primary anymore.
+// First statement of connection is executed again on connection to new primary, if server hasn't executed it
+type pooledConn struct {
+	*pgxpool.Conn
+	invalidate func()
+	// reacquire is reset once first statement is executed
+	reacquire func(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// txConn executes statements in transaction of pooled connection, they are never
+// retried, because transaction is gone with connection
+type txConn struct {
+	tx         pgx.Tx
+	invalidate func()
+}
+
+// checkedRow executes query on Scan, so the first query of connection can be retried
+type checkedRow struct {
+	ctx      context.Context
+	execute  func(ctx context.Context, operation func() error) error
+	queryRow func() pgx.Row
+}
+
+func (pc *pooledConn) Close(ctx context.Context) error {
+	pc.Release()
+	return nil
+}
+
+func (pc *pooledConn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := pc.execute(ctx, func() error {
+		var err error
+		tag, err = pc.Conn.Exec(ctx, sql, arguments...)
+		return err
+	})
+	return tag, err
+}
+
+func (pc *pooledConn) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := pc.execute(ctx, func() error {
+		var err error
+		rows, err = pc.Conn.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (pc *pooledConn) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return checkedRow{ctx: ctx, execute: pc.execute, queryRow: func() pgx.Row {
+		return pc.Conn.QueryRow(ctx, sql, args...)
+	}}
+}
+
+func (pc *pooledConn) ExecStatements(ctx context.Context, statements ...string) error {
+	return pc.execute(ctx, func() error {
+		return pc.execStatements(ctx, statements)
+	})
+}
+
+func (pc *pooledConn) execStatements(ctx context.Context, statements []string) error {
+	if len(statements) == 1 {
+		return pc.execStatement(ctx, pc.Conn.Conn().PgConn(), statements[0])
+	}
+	tx, err := pc.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	for _, statement := range statements {
+		if err = pc.execStatement(ctx, tx.Conn().PgConn(), statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Unlike simple protocol, extended protocol refuses to parse several commands in one statement
+func (pc *pooledConn) execStatement(ctx context.Context, conn *pgconn.PgConn, statement string) error {
+	_, err := conn.ExecParams(ctx, statement, nil, nil, nil, nil).Close()
+	return err
+}
+
+func (pc *pooledConn) PrepareStatement(ctx context.Context, sql string) error {
+	return pc.execute(ctx, func() error {
+		_, err := pc.Conn.Conn().PgConn().Prepare(ctx, "", sql, nil)
+		return err
+	})
+}
+
+// InTx begins transaction, which may be retried as the first statement of connection,
+// statements of fn are not retried
+func (pc *pooledConn) InTx(ctx context.Context, fn func(Conn) error) error {
+	var tx pgx.Tx
+	err := pc.execute(ctx, func() error {
+		var err error
+		tx, err = pc.Conn.Begin(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return runInTx(ctx, tx, fn, pc.invalidate)
+}
+
+func (r checkedRow) Scan(dest ...interface{}) error {
+	return r.execute(r.ctx, func() error {
+		return r.queryRow().Scan(dest...)
+	})
+}
+
+// runInTx commits transaction if fn succeeds, otherwise transaction is rolled back
+// and error of rollback is returned along with error of fn
+func runInTx(ctx context.Context, tx pgx.Tx, fn func(Conn) error, invalidate func()) (err error) {
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollbackErr := tx.Rollback(ctx); rollbackErr != nil && !errors.Is(rollbackErr, pgx.ErrTxClosed) {
+			err = errors.Join(err, fmt.Errorf("cannot rollback transaction: %w", rollbackErr))
+		}
+	}()
+	if err = fn(&txConn{tx: tx, invalidate: invalidate}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Transaction doesn't own connection, it is released by pooled connection
+func (tc *txConn) Close(ctx context.Context) error {
+	return nil
+}
+
+func (tc *txConn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := tc.execute(ctx, func() error {
+		var err error
+		tag, err = tc.tx.Exec(ctx, sql, arguments...)
+		return err
+	})
+	return tag, err
+}
+
+func (tc *txConn) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := tc.execute(ctx, func() error {
+		var err error
+		rows, err = tc.tx.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (tc *txConn) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return checkedRow{ctx: ctx, execute: tc.execute, queryRow: func() pgx.Row {
+		return tc.tx.QueryRow(ctx, sql, args...)
+	}}
+}
+
+// Statements are already executed in transaction, so no nested one is needed
+func (tc *txConn) ExecStatements(ctx context.Context, statements ...string) error {
+	return tc.execute(ctx, func() error {
+		for _, statement := range statements {
+			if _, err := tc.tx.Conn().PgConn().ExecParams(ctx, statement, nil, nil, nil, nil).Close(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (tc *txConn) PrepareStatement(ctx context.Context, sql string) error {
+	return tc.execute(ctx, func() error {
+		_, err := tc.tx.Conn().PgConn().Prepare(ctx, "", sql, nil)
+		return err
+	})
+}
+
+// Nested transaction is a savepoint
+func (tc *txConn) InTx(ctx context.Context, fn func(Conn) error) error {
+	var tx pgx.Tx
+	err := tc.execute(ctx, func() error {
+		var err error
+		tx, err = tc.tx.Begin(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return runInTx(ctx, tx, fn, tc.invalidate)
+}
+
+// Pools are invalidated on failover, but statement is not retried without reconnect
+func (tc *txConn) execute(ctx context.Context, operation func() error) error {
+	return withRetry(ctx, tc.invalidate, nil, isNotExecuted, operation)
+}
+
+// Statement is not retried once connection has been used, session may already have its state
+func (pc *pooledConn) execute(ctx context.Context, operation func() error) error {
+	reacquire := pc.reacquire
+	pc.reacquire = nil
+	var reconnect func(context.Context) error
+	if reacquire != nil {
+		reconnect = func(ctx context.Context) error {
+			conn, err := reacquire(ctx)
+			if err != nil {
+				return err
+			}
+			pc.Conn.Release()
+			pc.Conn = conn
+			return nil
+		}
+	}
+	return withRetry(ctx, pc.invalidate, reconnect, isNotExecuted, operation)
+}
+
+// withRetry invalidates pools if operation fails because connection is lost or server
+// is not primary anymore and executes operation once more after reconnect, so failover
+// is transparent for request. Operation is retried only if retryable reports that server
+// hasn't executed it, and it is not retried if reconnect is nil
+func withRetry(ctx context.Context, invalidate func(), reconnect func(context.Context) error,
+	retryable func(error) bool, operation func() error) error {
+	err := operation()
+	if !isFailover(ctx, err) {
+		return err
+	}
+	invalidate()
+	if reconnect == nil || !retryable(err) {
+		return err
+	}
+	if reconnectErr := reconnect(ctx); reconnectErr != nil {
+		log.Warn("Connection to new primary cannot be established", zap.Error(reconnectErr))
+		return err
+	}
+	log.Info("Connection to primary has been lost, request is retried on new connection", zap.Error(err))
+	err = operation()
+	if isFailover(ctx, err) {
+		invalidate()
+	}
+	return err
+}
+
+// Cancelled request closes the connection too, so it is not a reason to reset pools
+func isFailover(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() == nil && (isConnectionLost(err) || isPrimaryLost(err))
+}
+
+func isConnectionLost(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Statement is not executed if server rejects it as former primary or it is not sent at all,
+// otherwise lost connection may hide executed statement, which must not be executed twice
+func isNotExecuted(err error) bool {
+	return isPrimaryLost(err) || pgconn.SafeToRetry(err)
+}
+
+// Read only transaction and shutdown errors are returned by former primary after failover
+func isPrimaryLost(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return slices.Contains([]string{readOnlyTransaction, adminShutdown, crashShutdown, cannotConnectNow}, pgErr.Code)
+}
+
+type poolKey struct {
+	hosts    string
+	database string
+	username string
+	password string
+}
+
+type cachedPool struct {
+	pool     *pgxpool.Pool
+	lastUsed time.Time
+}
+
+// poolCache keeps pgxpool.Pool per database and user. Pools, which are
+// not used longer than idle timeout, are closed on next acquire
+type poolCache struct {
+	mutex        sync.Mutex
+	pools        map[poolKey]*cachedPool
+	onInvalidate func()
+}
+
+func newPoolCache() *poolCache {
+	return &poolCache{pools: make(map[poolKey]*cachedPool)}
+}
+
+func (pc *poolCache) acquire(ctx context.Context, key poolKey, connUrl string) (*pgxpool.Conn, error) {
+	pool, err := pc.getPool(ctx, key, connUrl)
+	if err != nil {
+		return nil, err
+	}
+	return pool.Acquire(ctx)
+}
+
+func (pc *poolCache) getPool(ctx context.Context, key poolKey, connUrl string) (*pgxpool.Pool, error) {
+	if pool := pc.getCachedPool(key); pool != nil {
+		return pool, nil
+	}
+
+	// Pool is created outside the lock, so slow server doesn't block requests to other databases
+	pool, err := newPool(ctx, connUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	now := time.Now()
+	if cached, ok := pc.pools[key]; ok {
+		// Concurrent request has already created the pool
+		go pool.Close()
+		cached.lastUsed = now
+		return cached.pool, nil
+	}
+	if len(pc.pools) >= poolMaxPools {
+		pc.evictLeastRecentlyUsed()
+	}
+	log.Debug(fmt.Sprintf("Connection pool has been created for database %s and user %s", key.database, key.username))
+	pc.pools[key] = &cachedPool{pool: pool, lastUsed: now}
+	return pool, nil
+}
+
+func (pc *poolCache) getCachedPool(key poolKey) *pgxpool.Pool {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	now := time.Now()
+	pc.evictIdle(now)
+	if cached, ok := pc.pools[key]; ok {
+		cached.lastUsed = now
+		return cached.pool
+	}
+	return nil
+}
+
+// newPool doesn't establish connections, they are established on acquire
+func newPool(ctx context.Context, connUrl string) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(connUrl)
+	if err != nil {
+		return nil, err
+	}
+	config.MaxConns = int32(poolMaxConns)
+	config.MaxConnIdleTime = poolIdleTimeout
+	config.MaxConnLifetime = poolMaxConnLifetime
+	config.LazyConnect = true
+	return pgxpool.ConnectConfig(ctx, config)
+}
+
+// invalidate closes all pools, so new connections are validated and established to current primary
+func (pc *poolCache) invalidate() {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	if pc.onInvalidate != nil {
+		pc.onInvalidate()
+	}
+	if len(pc.pools) == 0 {
+		return
+	}
+	log.Warn(fmt.Sprintf("Connection to postgres is lost or server is not primary, %d connection pools are reset", len(pc.pools)))
+	for key, cached := range pc.pools {
+		pc.closePool(key, cached)
+	}
+}
+
+func (pc *poolCache) evictIdle(now time.Time) {
+	for key, cached := range pc.pools {
+		if now.Sub(cached.lastUsed) >= poolIdleTimeout && cached.pool.Stat().AcquiredConns() == 0 {
+			pc.closePool(key, cached)
+		}
+	}
+}
+
+func (pc *poolCache) evictLeastRecentlyUsed() {
+	var lruKey poolKey
+	var lru *cachedPool
+	for key, cached := range pc.pools {
+		if cached.pool.Stat().AcquiredConns() > 0 {
+			continue
+		}
+		if lru == nil || cached.lastUsed.Before(lru.lastUsed) {
+			lruKey, lru = key, cached
+		}
+	}
+	if lru != nil {
+		pc.closePool(lruKey, lru)
+	} else {
+		log.Warn(fmt.Sprintf("All %d connection pools are in use, pools limit is exceeded", len(pc.pools)))
+	}
+}
+
+func (pc *poolCache) closePool(key poolKey, cached *cachedPool) {
+	delete(pc.pools, key)
+	// Close waits for acquired connections, so it is not done under the lock
+	go cached.pool.Close()
+	log.Debug(fmt.Sprintf("Connection pool has been closed for database %s and user %s", key.database, key.username), zap.Time("lastUsed", cached.lastUsed))
+}
+
+func (pc *poolCache) stats() []PoolStat {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	stats := make([]PoolStat, 0, len(pc.pools))
+	for key, cached := range pc.pools {
+		stat := cached.pool.Stat()
+		stats = append(stats, PoolStat{
+			Hosts:           key.hosts,
+			Database:        key.database,
+			User:            key.username,
+			MaxConns:        stat.MaxConns(),
+			TotalConns:      stat.TotalConns(),
+			AcquiredConns:   stat.AcquiredConns(),
+			IdleConns:       stat.IdleConns(),
+			AcquireCount:    stat.AcquireCount(),
+			NewConnsCount:   stat.NewConnsCount(),
+			AcquireDuration: stat.AcquireDuration().String(),
+			LastUsed:        cached.lastUsed,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Database == stats[j].Database {
+			return stats[i].User < stats[j].User
+		}
+		return stats[i].Database < stats[j].Database
+	})
+	return stats
+}
+
+func (pc *poolCache) close() {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	for key, cached := range pc.pools {
+		delete(pc.pools, key)
+		cached.pool.Close()
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// notSentError is returned by pgconn if statement fails before it is sent
+type notSentError struct{}
+
+func (notSentError) Error() string {
+	return "failed to write"
+}
+
+func (notSentError) SafeToRetry() bool {
+	return true
+}
+
+func (notSentError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+func TestWithRetry(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		name         string
+		ctx          context.Context
+		errors       []error
+		noReconnect  bool
+		reconnectErr error
+		acquire      bool
+		executions   int
+		invalidated  int
+		failed       bool
+	}{
+		{name: "success", ctx: context.Background(), errors: []error{nil}, executions: 1},
+		{name: "read only transaction", ctx: context.Background(), errors: []error{&pgconn.PgError{Code: readOnlyTransaction}, nil}, executions: 2, invalidated: 1},
+		{name: "admin shutdown", ctx: context.Background(), errors: []error{&pgconn.PgError{Code: adminShutdown}, nil}, executions: 2, invalidated: 1},
+		{name: "not sent", ctx: context.Background(), errors: []error{notSentError{}, nil}, executions: 2, invalidated: 1},
+		{name: "connection lost", ctx: context.Background(), errors: []error{io.ErrUnexpectedEOF}, executions: 1, invalidated: 1, failed: true},
+		{name: "acquire on lost connection", ctx: context.Background(), errors: []error{io.ErrUnexpectedEOF, nil}, acquire: true, executions: 2, invalidated: 1},
+		{name: "retried once", ctx: context.Background(), errors: []error{&pgconn.PgError{Code: crashShutdown}, io.EOF}, executions: 2, invalidated: 2, failed: true},
+		{name: "other error", ctx: context.Background(), errors: []error{&pgconn.PgError{Code: "42P01"}}, executions: 1, failed: true},
+		{name: "cancelled request", ctx: cancelled, errors: []error{io.ErrUnexpectedEOF}, executions: 1, failed: true},
+		{name: "used connection", ctx: context.Background(), errors: []error{io.EOF}, noReconnect: true, executions: 1, invalidated: 1, failed: true},
+		{name: "reconnect failed", ctx: context.Background(), errors: []error{io.EOF}, reconnectErr: errors.New("connection refused"), executions: 1, invalidated: 1, failed: true},
+	}
+	for _, tc := range cases {
+		executions, invalidated := 0, 0
+		reconnect := func(context.Context) error { return tc.reconnectErr }
+		if tc.noReconnect {
+			reconnect = nil
+		}
+		retryable := isNotExecuted
+		if tc.acquire {
+			retryable = func(error) bool { return true }
+		}
+		err := withRetry(tc.ctx, func() { invalidated++ }, reconnect, retryable, func() error {
+			executions++
+			return tc.errors[executions-1]
+		})
+		if (err != nil) != tc.failed {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if executions != tc.executions {
+			t.Errorf("%s: operation is executed %d times, expected %d", tc.name, executions, tc.executions)
+		}
+		if invalidated != tc.invalidated {
+			t.Errorf("%s: pools are invalidated %d times, expected %d", tc.name, invalidated, tc.invalidated)
+		}
+	}
+}
+
+type fakeTx struct {
+	pgx.Tx
+	rollbackErr error
+	committed   bool
+	rolledBack  bool
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	tx.rolledBack = true
+	return tx.rollbackErr
+}
+
+func TestRunInTx(t *testing.T) {
+	ctx := context.Background()
+	fnErr := errors.New("statement failed")
+	rollbackErr := errors.New("connection is closed")
+
+	tx := &fakeTx{}
+	if err := runInTx(ctx, tx, func(Conn) error { return nil }, func() {}); err != nil || !tx.committed || tx.rolledBack {
+		t.Errorf("successful transaction: err %v, committed %v, rolled back %v", err, tx.committed, tx.rolledBack)
+	}
+
+	tx = &fakeTx{}
+	if err := runInTx(ctx, tx, func(Conn) error { return fnErr }, func() {}); err != fnErr || tx.committed || !tx.rolledBack {
+		t.Errorf("failed transaction: err %v, committed %v, rolled back %v", err, tx.committed, tx.rolledBack)
+	}
+
+	tx = &fakeTx{rollbackErr: rollbackErr}
+	err := runInTx(ctx, tx, func(Conn) error { return fnErr }, func() {})
+	if !errors.Is(err, fnErr) || !errors.Is(err, rollbackErr) {
+		t.Errorf("error of rollback is not reported: %v", err)
+	}
+
+	tx = &fakeTx{rollbackErr: pgx.ErrTxClosed}
+	if err = runInTx(ctx, tx, func(Conn) error { return fnErr }, func() {}); err != fnErr {
+		t.Errorf("closed transaction is reported as rollback error: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// SchemaPreparer returns connections to default database, prepare query is executed on the
+// first of them, so tables of controller are created on first use and postgres is not required on start
+type SchemaPreparer struct {
+	pgClient *Client
+	name     string
+	query    string
+	mutex    sync.Mutex
+	prepared bool
+}
+
+func NewSchemaPreparer(pgClient *Client, name, query string) *SchemaPreparer {
+	return &SchemaPreparer{pgClient: pgClient, name: name, query: query}
+}
+
+// GetConnection executes prepare query until it succeeds once
+func (sp *SchemaPreparer) GetConnection(ctx context.Context) (Conn, error) {
+	conn, err := sp.pgClient.GetConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+	if !sp.prepared {
+		if _, err = conn.Exec(ctx, sp.query); err != nil {
+			conn.Close(ctx)
+			return nil, fmt.Errorf("cannot prepare %s: %w", sp.name, err)
+		}
+		sp.prepared = true
+	}
+	return conn, nil
+}
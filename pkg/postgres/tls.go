@@ -0,0 +1,99 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	SSLModeDisable    = "disable"
+	SSLModeAllow      = "allow"
+	SSLModePrefer     = "prefer"
+	SSLModeRequire    = "require"
+	SSLModeVerifyCA   = "verify-ca"
+	SSLModeVerifyFull = "verify-full"
+)
+
+var sslModes = []string{SSLModeDisable, SSLModeAllow, SSLModePrefer, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull}
+
+// TLSConfig is libpq compatible TLS configuration of postgres connections.
+// Server certificate is verified against system roots if RootCert is empty
+type TLSConfig struct {
+	Mode     string
+	RootCert string
+	Cert     string
+	Key      string
+}
+
+// NewTLSConfig resolves sslmode, legacy ssl flag "on" means require if sslmode is not set,
+// otherwise prefer is used as default of libpq
+func NewTLSConfig(ssl, mode, rootCert, cert, key string) TLSConfig {
+	if len(mode) == 0 {
+		mode = SSLModePrefer
+		if ssl == "on" {
+			mode = SSLModeRequire
+		}
+	}
+	return TLSConfig{Mode: mode, RootCert: rootCert, Cert: cert, Key: key}
+}
+
+// Validate checks sslmode and loads configured files,
+// so broken configuration is reported on start instead of first connection
+func (tc TLSConfig) Validate() error {
+	if !slices.Contains(sslModes, tc.Mode) {
+		return fmt.Errorf("sslmode %s is not supported, allowed values: %s", tc.Mode, strings.Join(sslModes, ", "))
+	}
+	if tc.Mode == SSLModeDisable && (len(tc.RootCert) > 0 || len(tc.Cert) > 0) {
+		return fmt.Errorf("root certificate and client certificate cannot be used with sslmode %s", tc.Mode)
+	}
+	if len(tc.RootCert) > 0 {
+		data, err := os.ReadFile(tc.RootCert)
+		if err != nil {
+			return fmt.Errorf("cannot read root certificate %s: %w", tc.RootCert, err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(data) {
+			return fmt.Errorf("root certificate %s doesn't contain PEM certificates", tc.RootCert)
+		}
+	}
+	if (len(tc.Cert) > 0) != (len(tc.Key) > 0) {
+		return fmt.Errorf("both client certificate and client key must be set")
+	}
+	if len(tc.Cert) > 0 {
+		if _, err := tls.LoadX509KeyPair(tc.Cert, tc.Key); err != nil {
+			return fmt.Errorf("cannot load client certificate %s with key %s: %w", tc.Cert, tc.Key, err)
+		}
+	}
+	return nil
+}
+
+func (tc TLSConfig) urlParams() string {
+	params := url.Values{}
+	params.Set("sslmode", tc.Mode)
+	if len(tc.RootCert) > 0 {
+		params.Set("sslrootcert", tc.RootCert)
+	}
+	if len(tc.Cert) > 0 {
+		params.Set("sslcert", tc.Cert)
+		params.Set("sslkey", tc.Key)
+	}
+	return params.Encode()
+}
@@ -0,0 +1,527 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	ActionCreate     = "createPublication"
+	ActionRecreate   = "recreatePublication"
+	ActionAddTable   = "addTable"
+	ActionDropTable  = "dropTable"
+	ActionAlterTable = "alterTable"
+	ActionAddSchema  = "addSchema"
+	ActionDropSchema = "dropSchema"
+	ActionOptions    = "setOptions"
+	ActionOwner      = "setOwner"
+)
+
+// errScratchRollback rolls back transaction of scratch publication
+var errScratchRollback = errors.New("scratch publication is rolled back")
+
+type ApplyResult struct {
+	Publication string   `json:"publication"`
+	Database    string   `json:"database"`
+	DryRun      bool     `json:"dryRun,omitempty"`
+	Changed     bool     `json:"changed"`
+	Changes     []Change `json:"changes"`
+	Statements  []string `json:"statements,omitempty"`
+}
+
+type Change struct {
+	Action string `json:"action"`
+	Object string `json:"object,omitempty"`
+}
+
+// publicationState is a definition of publication resolved against catalog,
+// tables are keyed by quoted "schema"."table" name
+type publicationState struct {
+	exists    bool
+	allTables bool
+	owner     string
+	options   PublicationOptions
+	tables    map[string]TableEntry
+	schemas   []string
+}
+
+// applyPublication converges publication to spec, only differing members
+// and options are changed, all statements are executed in one transaction.
+// Current state is only read in dry run, statements are returned without execution.
+// Change of allTables drops and creates publication again, subscribers lose
+// their position, so it is rejected with conflict unless allowRecreate is set
+func (pc *PublicationController) applyPublication(ctx context.Context, database, publication string, spec PublicationSpec, dryRun, allowRecreate bool) (ApplyResult, error) {
+	log := utils.ContextLogger(ctx)
+
+	result := ApplyResult{Publication: publication, Database: database, DryRun: dryRun, Changes: make([]Change, 0)}
+	err := validatePublication(publication, database)
+	if err == nil {
+		err = validatePublicationSpec(spec)
+	}
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return result, err
+	}
+
+	log.Info(fmt.Sprintf("Publication %s apply started for database %s", publication, database))
+	conn, err := pc.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return result, apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	err = checkRowFilters(ctx, conn, spec.Tables)
+	if err != nil {
+		return result, err
+	}
+	desired, err := resolveSpec(ctx, conn, spec)
+	if err != nil {
+		return result, err
+	}
+	current, err := getPublicationState(ctx, conn, publication)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot get state of publication %s for database %s", publication, database), zap.Error(err))
+		return result, apierror.FromDB(err, "cannot get state of publication %s for database %s", publication, database)
+	}
+	if current.exists && current.allTables != desired.allTables && !allowRecreate {
+		return result, apierror.Conflict("publication %s must be recreated to change allTables, set allowRecreate to confirm", publication)
+	}
+	err = deparseRowFilters(ctx, conn, current, desired)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot deparse row filters of publication %s for database %s", publication, database), zap.Error(err))
+		return result, apierror.FromDB(err, "cannot deparse row filters of publication %s for database %s", publication, database)
+	}
+
+	result.Changes, result.Statements = diffPublication(publication, current, desired)
+	if len(result.Statements) == 0 {
+		log.Info(fmt.Sprintf("Publication %s is up to date in database %s", publication, database))
+		return result, nil
+	}
+	if dryRun {
+		log.Info(fmt.Sprintf("Publication %s apply for database %s is planned with %d changes", publication, database, len(result.Changes)))
+		return result, nil
+	}
+
+	err = execStatements(ctx, conn, result.Statements...)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot apply publication %s for database %s", publication, database), zap.Error(err))
+		return result, apierror.FromDB(err, "cannot apply publication %s for database %s", publication, database)
+	}
+	result.Changed = true
+	log.Info(fmt.Sprintf("Publication %s has been applied for database %s with %d changes", publication, database, len(result.Changes)))
+	return result, nil
+}
+
+func diffPublication(publication string, current, desired publicationState) ([]Change, []string) {
+	changes := make([]Change, 0)
+	statements := make([]string, 0)
+	options := &desired.options
+
+	if !current.exists || current.allTables != desired.allTables {
+		action := ActionCreate
+		if current.exists {
+			action = ActionRecreate
+			statements = append(statements, getPubDropQuery(publication))
+		}
+		changes = append(changes, Change{Action: action, Object: publication})
+		tables := sortedTables(desired.tables)
+		if desired.allTables {
+			statements = append(statements, getPubCreateAllTablesQuery(publication, options))
+		} else if len(tables) == 0 && len(desired.schemas) == 0 {
+			statements = append(statements, getPubCreateEmptyQuery(publication, options))
+		} else {
+			statements = append(statements, getPubCreateQuery(publication, tables, desired.schemas, options))
+		}
+		if len(desired.owner) > 0 {
+			changes = append(changes, Change{Action: ActionOwner, Object: desired.owner})
+			statements = append(statements, getPubAlterOwnerQuery(publication, desired.owner))
+		}
+		return changes, statements
+	}
+
+	dropTables := make([]TableEntry, 0)
+	addTables := make([]TableEntry, 0)
+	for _, key := range sortedKeys(current.tables) {
+		table := current.tables[key]
+		wanted, ok := desired.tables[key]
+		if !ok {
+			changes = append(changes, Change{Action: ActionDropTable, Object: key})
+			dropTables = append(dropTables, TableEntry{Schema: table.Schema, Name: table.Name})
+		} else if !sameTableDefinition(table, wanted) {
+			// Column list and row filter cannot be changed in place for one table
+			changes = append(changes, Change{Action: ActionAlterTable, Object: key})
+			dropTables = append(dropTables, TableEntry{Schema: table.Schema, Name: table.Name})
+			addTables = append(addTables, wanted)
+		}
+	}
+	for _, key := range sortedKeys(desired.tables) {
+		if _, ok := current.tables[key]; !ok {
+			changes = append(changes, Change{Action: ActionAddTable, Object: key})
+			addTables = append(addTables, desired.tables[key])
+		}
+	}
+
+	dropSchemas := make([]string, 0)
+	for _, schema := range current.schemas {
+		if !slices.Contains(desired.schemas, schema) {
+			changes = append(changes, Change{Action: ActionDropSchema, Object: schema})
+			dropSchemas = append(dropSchemas, schema)
+		}
+	}
+	addSchemas := make([]string, 0)
+	for _, schema := range desired.schemas {
+		if !slices.Contains(current.schemas, schema) {
+			changes = append(changes, Change{Action: ActionAddSchema, Object: schema})
+			addSchemas = append(addSchemas, schema)
+		}
+	}
+
+	statements = append(statements,
+		getPubAlterDropQuery(publication, dropTables, dropSchemas),
+		getPubAlterAddQuery(publication, addTables, addSchemas))
+
+	if !sameOptions(current.options, desired.options) {
+		changes = append(changes, Change{Action: ActionOptions})
+		statements = append(statements, getPubAlterOptionsQuery(publication, options))
+	}
+	if len(desired.owner) > 0 && desired.owner != current.owner {
+		changes = append(changes, Change{Action: ActionOwner, Object: desired.owner})
+		statements = append(statements, getPubAlterOwnerQuery(publication, desired.owner))
+	}
+
+	return changes, nonEmptyQueries(statements...)
+}
+
+func getPublicationState(ctx context.Context, conn postgres.Conn, publication string) (publicationState, error) {
+	state := publicationState{tables: make(map[string]TableEntry), schemas: make([]string, 0)}
+	rows, err := conn.Query(ctx, getPubStateQuery(), publication)
+	if err != nil {
+		return state, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return state, rows.Err()
+	}
+	var pubInsert, pubUpdate, pubDelete, pubTruncate, pubViaRoot bool
+	err = rows.Scan(&state.allTables, &state.owner, &pubInsert, &pubUpdate, &pubDelete, &pubTruncate, &pubViaRoot)
+	if err != nil {
+		return state, err
+	}
+	rows.Close()
+	state.exists = true
+	state.options = PublicationOptions{Publish: make([]string, 0, len(publishActions)), PublishViaPartitionRoot: &pubViaRoot}
+	for i, enabled := range []bool{pubInsert, pubUpdate, pubDelete, pubTruncate} {
+		if enabled {
+			state.options.Publish = append(state.options.Publish, publishActions[i])
+		}
+	}
+
+	rows, err = conn.Query(ctx, getPubRelsQuery(), publication)
+	if err != nil {
+		return state, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table TableEntry
+		err = rows.Scan(&table.Schema, &table.Name, &table.Columns, &table.Where)
+		if err != nil {
+			return state, err
+		}
+		state.tables[tableKey(table)] = table
+	}
+	rows.Close()
+
+	rows, err = conn.Query(ctx, getPubNamespacesQuery(), publication)
+	if err != nil {
+		return state, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schema string
+		err = rows.Scan(&schema)
+		if err != nil {
+			return state, err
+		}
+		state.schemas = append(state.schemas, schema)
+	}
+	return state, rows.Err()
+}
+
+// deparseRowFilters replaces row filters of desired tables, which are members of
+// publication with filter, by the text of pg_get_expr. Filters are deparsed from
+// scratch publication, which is created in transaction and rolled back
+func deparseRowFilters(ctx context.Context, conn postgres.Conn, current, desired publicationState) error {
+	tables := make([]TableEntry, 0)
+	for _, key := range sortedKeys(desired.tables) {
+		table := desired.tables[key]
+		if member, ok := current.tables[key]; ok && len(member.Where) > 0 && len(table.Where) > 0 {
+			tables = append(tables, TableEntry{Schema: table.Schema, Name: table.Name, Where: table.Where})
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	var scratch publicationState
+	err := conn.InTx(ctx, func(tx postgres.Conn) error {
+		err := tx.ExecStatements(ctx, getPubCreateQuery(scratchPublication, tables, nil, nil))
+		if err != nil {
+			return err
+		}
+		scratch, err = getPublicationState(ctx, tx, scratchPublication)
+		if err != nil {
+			return err
+		}
+		return errScratchRollback
+	})
+	// Error of rollback is joined with errScratchRollback, so only bare one means success
+	if err != errScratchRollback {
+		return err
+	}
+	for key, table := range scratch.tables {
+		wanted := desired.tables[key]
+		wanted.Where = table.Where
+		desired.tables[key] = wanted
+	}
+	return nil
+}
+
+// Tables of spec are resolved against catalog, so plain and structured
+// entries and names without schema are compared with publication members
+func resolveSpec(ctx context.Context, conn postgres.Conn, spec PublicationSpec) (publicationState, error) {
+	log := utils.ContextLogger(ctx)
+
+	state := publicationState{
+		allTables: spec.AllTables,
+		owner:     spec.Owner,
+		options:   normalizeOptions(spec.Options),
+		tables:    make(map[string]TableEntry),
+		schemas:   spec.Schemas,
+	}
+	if state.schemas == nil {
+		state.schemas = make([]string, 0)
+	}
+	for _, entry := range spec.Tables {
+		table := entry
+		if entry.isPlain() {
+			var err error
+			table, err = parsePlainTable(entry.plain)
+			if err != nil {
+				return state, apierror.BadRequest("table %s cannot be parsed: %s, use structured entry with schema, name, columns and where", entry, err.Error())
+			}
+		}
+		name := pgx.Identifier{table.Name}
+		if len(table.Schema) > 0 {
+			name = pgx.Identifier{table.Schema, table.Name}
+		}
+		err := conn.QueryRow(ctx, getResolveTableQuery(), name.Sanitize()).Scan(&table.Schema, &table.Name)
+		if err == pgx.ErrNoRows {
+			return state, apierror.NotFound("table %s doesn't exist", entry)
+		} else if err != nil {
+			log.Error(fmt.Sprintf("cannot resolve table %s", entry), zap.Error(err))
+			return state, apierror.FromDB(err, "cannot resolve table %s", entry)
+		}
+		state.tables[tableKey(table)] = table
+	}
+	return state, nil
+}
+
+// Parses plain "schema.table (col1, col2) WHERE (expr)" entry. Quoted identifiers
+// keep their case and may contain any characters, unquoted ones are folded to lower case
+func parsePlainTable(plain string) (TableEntry, error) {
+	rest := plain
+	var table TableEntry
+	var err error
+	table.Name, rest, err = cutIdentifier(rest)
+	if err != nil {
+		return table, err
+	}
+	if after, ok := strings.CutPrefix(strings.TrimLeft(rest, " \t\n"), "."); ok {
+		table.Schema = table.Name
+		table.Name, rest, err = cutIdentifier(after)
+		if err != nil {
+			return table, err
+		}
+	}
+	rest = strings.TrimSpace(rest)
+	if after, ok := strings.CutPrefix(rest, "("); ok {
+		rest = after
+		for {
+			var column string
+			column, rest, err = cutIdentifier(rest)
+			if err != nil {
+				return table, err
+			}
+			table.Columns = append(table.Columns, column)
+			rest = strings.TrimSpace(rest)
+			if after, ok = strings.CutPrefix(rest, ","); ok {
+				rest = after
+				continue
+			}
+			if after, ok = strings.CutPrefix(rest, ")"); ok {
+				rest = strings.TrimSpace(after)
+				break
+			}
+			return table, fmt.Errorf("column list must be closed by parenthesis")
+		}
+	}
+	if len(rest) == 0 {
+		return table, nil
+	}
+	if len(rest) < len(whereKeyword) || !strings.EqualFold(rest[:len(whereKeyword)], whereKeyword) {
+		return table, fmt.Errorf("unexpected %q after table name", rest)
+	}
+	where := strings.TrimSpace(rest[len(whereKeyword):])
+	if !strings.HasPrefix(where, "(") || !strings.HasSuffix(where, ")") {
+		return table, fmt.Errorf("row filter must be enclosed in parentheses")
+	}
+	table.Where = strings.TrimSpace(where[1 : len(where)-1])
+	if len(table.Where) == 0 {
+		return table, fmt.Errorf("row filter must not be empty")
+	}
+	if err = validateRowFilter(table.Where); err != nil {
+		return table, fmt.Errorf("row filter is invalid: %w", err)
+	}
+	return table, nil
+}
+
+// cutIdentifier returns leading identifier of input and the rest of input
+func cutIdentifier(input string) (string, string, error) {
+	input = strings.TrimLeft(input, " \t\n")
+	if quoted, ok := strings.CutPrefix(input, "\""); ok {
+		var identifier strings.Builder
+		for {
+			part, rest, found := strings.Cut(quoted, "\"")
+			if !found {
+				return "", "", fmt.Errorf("quoted identifier is not terminated")
+			}
+			identifier.WriteString(part)
+			// Doubled quote is a quote inside identifier
+			if after, ok := strings.CutPrefix(rest, "\""); ok {
+				identifier.WriteString("\"")
+				quoted = after
+				continue
+			}
+			if identifier.Len() == 0 {
+				return "", "", fmt.Errorf("quoted identifier must not be empty")
+			}
+			return identifier.String(), rest, nil
+		}
+	}
+	end := strings.IndexFunc(input, func(ch rune) bool {
+		return !isIdentifierChar(ch) && ch != '$'
+	})
+	if end < 0 {
+		end = len(input)
+	}
+	if end == 0 || strings.HasPrefix(input, "$") {
+		return "", "", fmt.Errorf("identifier is expected at %q", input)
+	}
+	return strings.ToLower(input[:end]), input[end:], nil
+}
+
+// Absent options of spec mean defaults of postgres
+func normalizeOptions(options *PublicationOptions) PublicationOptions {
+	viaRoot := false
+	normalized := PublicationOptions{Publish: publishActions, PublishViaPartitionRoot: &viaRoot}
+	if options == nil {
+		return normalized
+	}
+	if options.Publish != nil {
+		normalized.Publish = options.Publish
+	}
+	if options.PublishViaPartitionRoot != nil {
+		normalized.PublishViaPartitionRoot = options.PublishViaPartitionRoot
+	}
+	return normalized
+}
+
+func sameOptions(first, second PublicationOptions) bool {
+	if len(first.Publish) != len(second.Publish) {
+		return false
+	}
+	for _, action := range first.Publish {
+		if !slices.Contains(second.Publish, action) {
+			return false
+		}
+	}
+	return *first.PublishViaPartitionRoot == *second.PublishViaPartitionRoot
+}
+
+// Column lists are compared regardless of order, row filters are deparsed by server
+// beforehand and compared regardless of whitespaces and enclosing parentheses
+func sameTableDefinition(first, second TableEntry) bool {
+	if len(first.Columns) != len(second.Columns) {
+		return false
+	}
+	for _, column := range first.Columns {
+		if !slices.Contains(second.Columns, column) {
+			return false
+		}
+	}
+	return normalizeRowFilter(first.Where) == normalizeRowFilter(second.Where)
+}
+
+func normalizeRowFilter(expr string) string {
+	expr = strings.Join(strings.Fields(expr), "")
+	for strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") && validateRowFilter(expr[1:len(expr)-1]) == nil {
+		expr = expr[1 : len(expr)-1]
+	}
+	return expr
+}
+
+func tableKey(table TableEntry) string {
+	return pgx.Identifier{table.Schema, table.Name}.Sanitize()
+}
+
+func sortedKeys(tables map[string]TableEntry) []string {
+	keys := make([]string, 0, len(tables))
+	for key := range tables {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func sortedTables(tables map[string]TableEntry) []TableEntry {
+	sorted := make([]TableEntry, 0, len(tables))
+	for _, key := range sortedKeys(tables) {
+		sorted = append(sorted, tables[key])
+	}
+	return sorted
+}
+
+func validatePublicationSpec(spec PublicationSpec) error {
+	if spec.AllTables && (len(spec.Tables) > 0 || len(spec.Schemas) > 0) {
+		return apierror.BadRequest("tables and schemas must be empty for publication of all tables")
+	}
+	err := validatePublicationOptions(spec.Options)
+	if err != nil {
+		return err
+	}
+	return validateTables(spec.Tables)
+}
@@ -0,0 +1,66 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publication
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePlainTable(t *testing.T) {
+	cases := map[string]TableEntry{
+		"events":                                {Name: "events"},
+		"Public.Events":                         {Schema: "public", Name: "events"},
+		"public.t(a,b) WHERE (x > 1)":           {Schema: "public", Name: "t", Columns: []string{"a", "b"}, Where: "x > 1"},
+		"public.t WHERE (x>1)":                  {Schema: "public", Name: "t", Where: "x>1"},
+		"public.t where ((x > 1) and (y < 2))":  {Schema: "public", Name: "t", Where: "(x > 1) and (y < 2)"},
+		`"my.schema".t`:                         {Schema: "my.schema", Name: "t"},
+		`"My ""quoted"" schema" . "T(1)" ("A")`: {Schema: `My "quoted" schema`, Name: "T(1)", Columns: []string{"A"}},
+		" public.t ( a , \"b,c\" ) ":            {Schema: "public", Name: "t", Columns: []string{"a", "b,c"}},
+	}
+	for plain, expected := range cases {
+		table, err := parsePlainTable(plain)
+		if err != nil {
+			t.Errorf("%q is not parsed: %v", plain, err)
+			continue
+		}
+		if table.Schema != expected.Schema || table.Name != expected.Name ||
+			!slices.Equal(table.Columns, expected.Columns) || table.Where != expected.Where {
+			t.Errorf("%q is parsed as %+v, expected %+v", plain, table, expected)
+		}
+	}
+}
+
+func TestParsePlainTableRejectsInvalid(t *testing.T) {
+	cases := []string{
+		"",
+		"public.",
+		`"unterminated.t`,
+		`"".t`,
+		"public.t(a, b",
+		"public.t()",
+		"public.t extra",
+		"public.t WHERE x > 1",
+		"public.t WHERE ()",
+		"public.t WHERE (x > 1) or (true)",
+		"public.t WHERE (x > 1); drop table t; --)",
+		"$1.t",
+	}
+	for _, plain := range cases {
+		if table, err := parsePlainTable(plain); err == nil {
+			t.Errorf("%q is parsed as %+v, expected error", plain, table)
+		}
+	}
+}
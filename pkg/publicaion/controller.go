@@ -17,29 +17,57 @@ package publication
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
+	"unicode"
 
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/audit"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/diagnostics"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/identity"
 	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
 	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v4"
 	"go.uber.org/zap"
 )
 
+// undefined_table is reported as not found even for row filter checks
+const undefinedTableCode = "42P01"
+
 var (
-	isNotFoundErr = pgx.ErrNoRows
+	publishActions = []string{"insert", "update", "delete", "truncate"}
+
+	inventoryParallelism = utils.GetEnvInt("PUB_INVENTORY_PARALLELISM", 4)
+	preflightEnabled     = utils.GetEnvBool("PUB_PREFLIGHT_ENABLED", true)
+	defaultIdentityMode  = utils.GetEnv("PUB_REPLICA_IDENTITY_MODE", identity.ModeIgnore)
 )
 
 type PublicationController struct {
-	pgClient *postgres.Client
+	pgClient    *postgres.Client
+	diagnostics *diagnostics.DiagnosticsController
 }
 
 type PublicationInfo struct {
 	Name     string             `json:"name"`
 	Owner    string             `json:"owner"`
 	Database string             `json:"database"`
+	Options  PublicationOptions `json:"options"`
 	Tables   map[string][]Table `json:"tables,omitempty"`
 }
 
+type PublicationOptions struct {
+	Publish                 []string `json:"publish,omitempty"`
+	PublishViaPartitionRoot *bool    `json:"publishViaPartitionRoot,omitempty"`
+}
+
+type DatabasePublications struct {
+	Database     string            `json:"database"`
+	Publications []PublicationInfo `json:"publications"`
+	Error        string            `json:"error,omitempty"`
+}
+
 type Table struct {
 	Name      string   `json:"name"`
 	Attr      []string `json:"attrNames"`
@@ -47,7 +75,7 @@ type Table struct {
 }
 
 func NewPublicationController(pgClient *postgres.Client) *PublicationController {
-	return &PublicationController{pgClient: pgClient}
+	return &PublicationController{pgClient: pgClient, diagnostics: diagnostics.NewDiagnosticsController(pgClient)}
 }
 
 func (pc *PublicationController) getPublication(ctx context.Context, request CommonRequest, withTables bool) (PublicationInfo, error) {
@@ -63,17 +91,14 @@ func (pc *PublicationController) getPublication(ctx context.Context, request Com
 	return pc.getPublicationInternal(ctx, publication, database, withTables)
 }
 
-// Error is only isNotFoundErr
+// Absent database or publication is reported as apierror with 404 status
 func (pc *PublicationController) getPublicationInternal(ctx context.Context, publication, database string, withTables bool) (PublicationInfo, error) {
 	log := utils.ContextLogger(ctx)
 
 	log.Info(fmt.Sprintf("Get publication %s for database %s", publication, database))
 	conn, err := pc.pgClient.GetConnectionToDb(ctx, database)
 	if err != nil {
-		if strings.Contains(err.Error(), "(SQLSTATE 3D000)") {
-			return PublicationInfo{}, isNotFoundErr
-		}
-		panic(err)
+		return PublicationInfo{}, apierror.FromConnection(err, "cannot connect to database %s", database)
 	}
 	defer conn.Close(ctx)
 
@@ -81,40 +106,175 @@ func (pc *PublicationController) getPublicationInternal(ctx context.Context, pub
 	rows, err := conn.Query(ctx, getPubGetQuery(), publication)
 	if err != nil {
 		log.Error(fmt.Sprintf("cannot get publication %s for database %s", publication, database))
-		panic(err)
+		return PublicationInfo{}, apierror.FromDB(err, "cannot get publication %s for database %s", publication, database)
 	}
 	defer rows.Close()
 
 	if rows.Next() {
-		err = rows.Scan(&pubInfo.Name, &pubInfo.Owner)
+		err = scanPublication(rows, &pubInfo)
 		if err != nil {
 			log.Error(fmt.Sprintf("cannot scan publication %s for database %s", publication, database))
-			panic(err)
+			return PublicationInfo{}, apierror.FromDB(err, "cannot scan publication %s for database %s", publication, database)
 		}
 	} else {
-		return PublicationInfo{}, isNotFoundErr
+		return PublicationInfo{}, apierror.NotFound("publication %s doesn't exist in database %s", publication, database)
 	}
 	pubInfo.Database = database
 
 	// Fill tables info
 	if withTables {
 		rows.Close()
-		rows, err := conn.Query(ctx, getPubGetTablesQuery(), publication)
+		pubInfo.Tables, err = getPublicationTables(ctx, conn, publication, database)
 		if err != nil {
-			log.Error(fmt.Sprintf("cannot get publication %s tables info for database %s", publication, database))
-			panic(err)
+			return PublicationInfo{}, err
 		}
+	}
+
+	log.Info(fmt.Sprintf("Publication %s has been get for database %s", publication, database))
+	return pubInfo, nil
+}
+
+// Absent database is reported as apierror with 404 status
+func (pc *PublicationController) listPublications(ctx context.Context, request ListRequest, withTables bool) ([]PublicationInfo, error) {
+	log := utils.ContextLogger(ctx)
 
-		tables, err := processTableRows(rows)
+	database := request.Database
+	if len(database) == 0 {
+		err := apierror.BadRequest("database must not be empty")
+		log.Error(err.Error(), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info(fmt.Sprintf("List publications for database %s", database))
+	conn, err := pc.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return nil, apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	rows, err := conn.Query(ctx, getPubListQuery(), request.Owner, request.Prefix)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot list publications for database %s", database))
+		return nil, apierror.FromDB(err, "cannot list publications for database %s", database)
+	}
+	defer rows.Close()
+
+	publications := make([]PublicationInfo, 0)
+	for rows.Next() {
+		pubInfo := PublicationInfo{Database: database}
+		err = scanPublication(rows, &pubInfo)
 		if err != nil {
-			log.Error(fmt.Sprintf("cannot scan publication %s tables info for database %s", publication, database))
-			panic(err)
+			log.Error(fmt.Sprintf("cannot scan publications for database %s", database))
+			return nil, apierror.FromDB(err, "cannot scan publications for database %s", database)
 		}
-		pubInfo.Tables = tables
+		publications = append(publications, pubInfo)
 	}
+	rows.Close()
 
-	log.Info(fmt.Sprintf("Publication %s has been get for database %s", publication, database))
-	return pubInfo, nil
+	if withTables {
+		for i := range publications {
+			publications[i].Tables, err = getPublicationTables(ctx, conn, publications[i].Name, database)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	log.Info(fmt.Sprintf("%d publications have been listed for database %s", len(publications), database))
+	return publications, nil
+}
+
+// Errors of particular databases are reported in the result and do not stop the inventory
+func (pc *PublicationController) listClusterPublications(ctx context.Context, request ListRequest, withTables bool) ([]DatabasePublications, error) {
+	log := utils.ContextLogger(ctx)
+
+	databases, err := pc.getDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Info(fmt.Sprintf("List publications for %d databases", len(databases)))
+
+	result := make([]DatabasePublications, len(databases))
+	parallelism := inventoryParallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, database := range databases {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, database string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result[i] = pc.listDatabasePublications(ctx, database, request, withTables)
+		}(i, database)
+	}
+	wg.Wait()
+
+	log.Info(fmt.Sprintf("Publications have been listed for %d databases", len(databases)))
+	return result, nil
+}
+
+func (pc *PublicationController) listDatabasePublications(ctx context.Context, database string, request ListRequest, withTables bool) DatabasePublications {
+	log := utils.ContextLogger(ctx)
+
+	dbPubs := DatabasePublications{Database: database, Publications: []PublicationInfo{}}
+	request.Database = database
+	publications, err := pc.listPublications(ctx, request, withTables)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot list publications for database %s", database), zap.Error(err))
+		dbPubs.Error = err.Error()
+		return dbPubs
+	}
+	dbPubs.Publications = publications
+	return dbPubs
+}
+
+func (pc *PublicationController) getDatabases(ctx context.Context) ([]string, error) {
+	log := utils.ContextLogger(ctx)
+
+	conn, err := pc.pgClient.GetConnection(ctx)
+	if err != nil {
+		return nil, apierror.FromConnection(err, "cannot connect to postgres")
+	}
+	defer conn.Close(ctx)
+
+	rows, err := conn.Query(ctx, getDatabasesListQuery())
+	if err != nil {
+		log.Error("cannot get list of databases")
+		return nil, apierror.FromDB(err, "cannot get list of databases")
+	}
+	defer rows.Close()
+
+	databases := make([]string, 0)
+	for rows.Next() {
+		var database string
+		err = rows.Scan(&database)
+		if err != nil {
+			log.Error("cannot scan list of databases")
+			return nil, apierror.FromDB(err, "cannot scan list of databases")
+		}
+		databases = append(databases, database)
+	}
+	return databases, nil
+}
+
+func getPublicationTables(ctx context.Context, conn postgres.Conn, publication, database string) (map[string][]Table, error) {
+	log := utils.ContextLogger(ctx)
+
+	rows, err := conn.Query(ctx, getPubGetTablesQuery(), publication)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot get publication %s tables info for database %s", publication, database))
+		return nil, apierror.FromDB(err, "cannot get publication %s tables info for database %s", publication, database)
+	}
+
+	tables, err := processTableRows(rows)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot scan publication %s tables info for database %s", publication, database))
+		return nil, apierror.FromDB(err, "cannot scan publication %s tables info for database %s", publication, database)
+	}
+	return tables, nil
 }
 
 func (pc *PublicationController) createPublication(ctx context.Context, request CommonRequest) error {
@@ -122,83 +282,106 @@ func (pc *PublicationController) createPublication(ctx context.Context, request
 
 	database := request.Database
 	publication := request.PubName
-	err := validatePublication(publication, database)
+	err := validateChangeRequest(request)
 	if err != nil {
 		log.Error(err.Error(), zap.Error(err))
 		return err
 	}
 	log.Info(fmt.Sprintf("Publication %s creation started for database %s", publication, database))
-	if pc.isPublicationExists(ctx, publication, database) {
+	exists, err := pc.isPublicationExists(ctx, publication, database)
+	if err != nil {
+		return err
+	}
+	if exists {
 		log.Info(fmt.Sprintf("Publication %s already exists in database %s", publication, database))
 		return nil
 	}
+	if preflightEnabled {
+		pc.runPreflight(ctx, database)
+	}
 
 	conn, err := pc.pgClient.GetConnectionToDb(ctx, database)
 	if err != nil {
-		panic(err)
+		return apierror.FromConnection(err, "cannot connect to database %s", database)
 	}
 	defer conn.Close(ctx)
 
-	tables := request.Tables
-	schemas := request.Schemas
-	if len(tables) == 0 && len(schemas) == 0 {
-		log.Debug(getPubCreateQuery(publication, tables, schemas))
-		_, err = conn.Exec(ctx, getPubCreateAllTablesQuery(publication))
-		if err != nil {
-			log.Error(fmt.Sprintf("cannot create publication %s for database %s", publication, database))
-			panic(err)
-		}
-	} else {
-		log.Debug(getPubCreateQuery(publication, tables, schemas))
-		_, err = conn.Exec(ctx, getPubCreateQuery(publication, tables, schemas))
-		if err != nil {
-			log.Error(fmt.Sprintf("cannot create publication %s for database %s for tables %s", publication, database, tables))
-			panic(err)
-		}
+	err = checkRowFilters(ctx, conn, request.Tables)
+	if err != nil {
+		return err
+	}
+	fixQueries, err := checkReplicaIdentity(ctx, conn, request, len(request.Tables) == 0 && len(request.Schemas) == 0)
+	if err != nil {
+		return err
+	}
+
+	err = execStatements(ctx, conn, getCreateStatements(request, fixQueries)...)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot create publication %s for database %s for tables %s", publication, database, request.Tables), zap.Error(err))
+		return apierror.FromDB(err, "cannot create publication %s for database %s", publication, database)
 	}
 
 	log.Info(fmt.Sprintf("Publication %s has been created for database %s", publication, database))
 	return nil
 }
 
+// Preflight is advisory, its findings and errors are only logged and never block creation
+func (pc *PublicationController) runPreflight(ctx context.Context, database string) {
+	log := utils.ContextLogger(ctx)
+
+	report, err := pc.diagnostics.RunPreflight(ctx, database, "")
+	if err != nil {
+		log.Warn(fmt.Sprintf("Replication readiness checks cannot be executed for database %s", database), zap.Error(err))
+		return
+	}
+	diagnostics.LogReport(ctx, report)
+}
+
 func (pc *PublicationController) alterAddPublication(ctx context.Context, request CommonRequest) error {
 	log := utils.ContextLogger(ctx)
 
 	database := request.Database
 	publication := request.PubName
-	err := validatePublication(publication, database)
+	err := validateChangeRequest(request)
 	if err != nil {
 		log.Error(err.Error(), zap.Error(err))
 		return err
 	}
 	log.Info(fmt.Sprintf("Publication %s alter add started for database %s", publication, database))
-	if !pc.isPublicationExists(ctx, publication, database) {
+	exists, err := pc.isPublicationExists(ctx, publication, database)
+	if err != nil {
+		return err
+	}
+	if !exists {
 		log.Info(fmt.Sprintf("Publication %s doesn't exist in database %s", publication, database))
-		return isNotFoundErr
+		return apierror.NotFound("publication %s doesn't exist in database %s", publication, database)
 	}
 
-	tables := request.Tables
-	schemas := request.Schemas
-	if len(tables) == 0 && len(schemas) == 0 {
-		errMsg := fmt.Sprintf("Nothing to add to publication %s in database %s", publication, database)
-		log.Error(errMsg)
-		return fmt.Errorf("%s", errMsg)
+	if isEmptyAlterRequest(request) {
+		err = apierror.BadRequest("nothing to add to publication %s in database %s", publication, database)
+		log.Error(err.Error())
+		return err
 	}
 
 	conn, err := pc.pgClient.GetConnectionToDb(ctx, database)
 	if err != nil {
-		panic(err)
+		return apierror.FromConnection(err, "cannot connect to database %s", database)
 	}
 	defer conn.Close(ctx)
 
-	log.Debug(getPubAlterAddQuery(publication, tables, schemas))
-	_, err = conn.Exec(ctx, getPubAlterAddQuery(publication, tables, schemas))
+	err = checkRowFilters(ctx, conn, request.Tables)
+	if err != nil {
+		return err
+	}
+	fixQueries, err := checkReplicaIdentity(ctx, conn, request, false)
+	if err != nil {
+		return err
+	}
+
+	err = execStatements(ctx, conn, getAlterStatements(request, fixQueries, getPubAlterAddQuery)...)
 	if err != nil {
 		log.Error(fmt.Sprintf("cannot alter add publication %s for database %s", publication, database), zap.Error(err))
-		if strings.Contains(err.Error(), "(SQLSTATE 42710)") {
-			return err
-		}
-		panic(err)
+		return apierror.FromDB(err, "cannot alter add publication %s for database %s", publication, database)
 	}
 
 	log.Info(fmt.Sprintf("Publication %s has been altered for database %s", publication, database))
@@ -210,45 +393,234 @@ func (pc *PublicationController) alterSetPublication(ctx context.Context, reques
 
 	database := request.Database
 	publication := request.PubName
-	err := validatePublication(publication, database)
+	err := validateChangeRequest(request)
 	if err != nil {
 		log.Error(err.Error(), zap.Error(err))
 		return err
 	}
 	log.Info(fmt.Sprintf("Publication %s alter set started for database %s", publication, database))
-	if !pc.isPublicationExists(ctx, publication, database) {
+	exists, err := pc.isPublicationExists(ctx, publication, database)
+	if err != nil {
+		return err
+	}
+	if !exists {
 		log.Info(fmt.Sprintf("Publication %s doesn't exist in database %s", publication, database))
-		return isNotFoundErr
+		return apierror.NotFound("publication %s doesn't exist in database %s", publication, database)
 	}
 
-	tables := request.Tables
-	schemas := request.Schemas
-	if len(tables) == 0 && len(schemas) == 0 {
-		errMsg := fmt.Sprintf("Nothing to add to publication %s in database %s", publication, database)
-		log.Error(errMsg)
-		return fmt.Errorf("%s", errMsg)
+	if isEmptyAlterRequest(request) {
+		err = apierror.BadRequest("nothing to add to publication %s in database %s", publication, database)
+		log.Error(err.Error())
+		return err
 	}
 
 	conn, err := pc.pgClient.GetConnectionToDb(ctx, database)
 	if err != nil {
-		panic(err)
+		return apierror.FromConnection(err, "cannot connect to database %s", database)
 	}
 	defer conn.Close(ctx)
 
-	log.Debug(getPubAlterSetQuery(publication, tables, schemas))
-	_, err = conn.Exec(ctx, getPubAlterSetQuery(publication, tables, schemas))
+	err = checkRowFilters(ctx, conn, request.Tables)
+	if err != nil {
+		return err
+	}
+	fixQueries, err := checkReplicaIdentity(ctx, conn, request, false)
+	if err != nil {
+		return err
+	}
+
+	err = execStatements(ctx, conn, getAlterStatements(request, fixQueries, getPubAlterSetQuery)...)
 	if err != nil {
 		log.Error(fmt.Sprintf("cannot alter set publication %s for database %s", publication, database), zap.Error(err))
-		if strings.Contains(err.Error(), "(SQLSTATE 42710)") {
-			return err
-		}
-		panic(err)
+		return apierror.FromDB(err, "cannot alter set publication %s for database %s", publication, database)
+	}
+
+	log.Info(fmt.Sprintf("Publication %s has been altered for database %s", publication, database))
+	return nil
+}
+
+// Tables and schemas which are not members of publication are skipped
+func (pc *PublicationController) alterDropPublication(ctx context.Context, request CommonRequest) error {
+	log := utils.ContextLogger(ctx)
+
+	database := request.Database
+	publication := request.PubName
+	err := validateDropRequest(request)
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return err
+	}
+	log.Info(fmt.Sprintf("Publication %s alter drop started for database %s", publication, database))
+	exists, err := pc.isPublicationExists(ctx, publication, database)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		log.Info(fmt.Sprintf("Publication %s doesn't exist in database %s", publication, database))
+		return apierror.NotFound("publication %s doesn't exist in database %s", publication, database)
+	}
+
+	if len(request.Tables) == 0 && len(request.Schemas) == 0 {
+		err = apierror.BadRequest("nothing to drop from publication %s in database %s", publication, database)
+		log.Error(err.Error())
+		return err
+	}
+
+	conn, err := pc.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	tableNames := trimTablesArgs(request.Tables)
+	tables, err := filterPublicationMembers(ctx, conn, publication, tableNames, prepareTables(tableNames), getPubHasTableQuery())
+	if err != nil {
+		return err
+	}
+	schemas, err := filterPublicationMembers(ctx, conn, publication, request.Schemas, prepareSchemas(request.Schemas), getPubHasSchemaQuery())
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 && len(schemas) == 0 {
+		log.Info(fmt.Sprintf("Requested tables and schemas are not members of publication %s in database %s", publication, database))
+		return nil
+	}
+
+	err = execStatements(ctx, conn, getPubAlterDropQuery(publication, tables, schemas))
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot alter drop publication %s for database %s", publication, database), zap.Error(err))
+		return apierror.FromDB(err, "cannot alter drop publication %s for database %s", publication, database)
 	}
 
 	log.Info(fmt.Sprintf("Publication %s has been altered for database %s", publication, database))
 	return nil
 }
 
+// Row filters are parsed and analyzed on server as WHERE clause of query to the table
+// before they are used in DDL, the query itself is not executed
+func checkRowFilters(ctx context.Context, conn postgres.Conn, tables []TableEntry) error {
+	log := utils.ContextLogger(ctx)
+
+	for _, table := range tables {
+		if table.isPlain() || len(table.Where) == 0 {
+			continue
+		}
+		query := fmt.Sprintf("SELECT 1 FROM %s", prepareStructuredTable(TableEntry{Schema: table.Schema, Name: table.Name, Where: table.Where}))
+		if err := conn.PrepareStatement(ctx, query); err != nil {
+			log.Error(fmt.Sprintf("row filter of table %s is invalid", table.Name), zap.Error(err))
+			apiErr := apierror.FromDB(err, "row filter of table %s is invalid", table.Name)
+			if len(apiErr.Code) > 0 && apiErr.Code != undefinedTableCode {
+				apiErr.Status = fiber.StatusBadRequest
+			}
+			return apiErr
+		}
+	}
+	return nil
+}
+
+// execStatements records and executes statements in a single transaction,
+// each statement is executed as exactly one command
+func execStatements(ctx context.Context, conn postgres.Conn, statements ...string) error {
+	log := utils.ContextLogger(ctx)
+
+	statements = nonEmptyQueries(statements...)
+	for _, statement := range statements {
+		log.Debug(statement)
+		audit.AddStatement(ctx, statement)
+	}
+	return conn.ExecStatements(ctx, statements...)
+}
+
+// Returns queries fixing replica identity of requested tables in fix mode,
+// tables breaking UPDATE and DELETE replication are refused in refuse mode.
+// All tables of database are checked for FOR ALL TABLES publication
+func checkReplicaIdentity(ctx context.Context, conn postgres.Conn, request CommonRequest, allTables bool) ([]string, error) {
+	log := utils.ContextLogger(ctx)
+
+	mode := getIdentityMode(request)
+	if (mode != identity.ModeRefuse && mode != identity.ModeFix) || !publishesChanges(request.Options) {
+		return nil, nil
+	}
+	if !allTables && len(request.Tables) == 0 && len(request.Schemas) == 0 {
+		return nil, nil
+	}
+
+	unsafe, err := identity.FindUnsafeTables(ctx, conn, prepareTables(trimTablesArgs(request.Tables)), request.Schemas)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot check replica identity of tables for publication %s", request.PubName), zap.Error(err))
+		return nil, err
+	}
+	if len(unsafe) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(unsafe))
+	for _, table := range unsafe {
+		names = append(names, table.String())
+	}
+	if mode == identity.ModeRefuse {
+		err = apierror.Conflict("tables %s have neither primary key nor replica identity, UPDATE and DELETE on them fail once published", strings.Join(names, ", "))
+		log.Error(err.Error())
+		return nil, err
+	}
+	log.Info(fmt.Sprintf("Replica identity FULL is required for tables %s of publication %s", strings.Join(names, ", "), request.PubName))
+	return identity.GetFixQueries(unsafe), nil
+}
+
+func getIdentityMode(request CommonRequest) string {
+	if len(request.IdentityMode) == 0 {
+		return defaultIdentityMode
+	}
+	return request.IdentityMode
+}
+
+// Statements of request are formed by the same functions for execution and plan,
+// replica identity fixes are executed in the same transaction before publication DDL
+func getCreateStatements(request CommonRequest, fixQueries []string) []string {
+	query := getPubCreateQuery(request.PubName, request.Tables, request.Schemas, request.Options)
+	if len(request.Tables) == 0 && len(request.Schemas) == 0 {
+		query = getPubCreateAllTablesQuery(request.PubName, request.Options)
+	}
+	return append(slices.Clone(fixQueries), query)
+}
+
+func getAlterStatements(request CommonRequest, fixQueries []string, alterQuery func(string, []TableEntry, []string) string) []string {
+	statements := append(slices.Clone(fixQueries), alterQuery(request.PubName, request.Tables, request.Schemas), getPubAlterOptionsQuery(request.PubName, request.Options))
+	return nonEmptyQueries(statements...)
+}
+
+func isEmptyAlterRequest(request CommonRequest) bool {
+	return len(request.Tables) == 0 && len(request.Schemas) == 0 && len(getPubAlterOptionsQuery(request.PubName, request.Options)) == 0
+}
+
+// Replica identity doesn't matter for publication, which publishes neither UPDATE nor DELETE
+func publishesChanges(options *PublicationOptions) bool {
+	if options == nil || options.Publish == nil {
+		return true
+	}
+	return slices.Contains(options.Publish, "update") || slices.Contains(options.Publish, "delete")
+}
+
+// Returns only those of items, which are members of publication according to memberQuery
+func filterPublicationMembers[T any](ctx context.Context, conn postgres.Conn, publication string, items []T, prepared []string, memberQuery string) ([]T, error) {
+	log := utils.ContextLogger(ctx)
+
+	members := make([]T, 0, len(items))
+	for i, name := range prepared {
+		var isMember bool
+		err := conn.QueryRow(ctx, memberQuery, publication, name).Scan(&isMember)
+		if err != nil {
+			log.Error(fmt.Sprintf("cannot check if %s is member of publication %s", name, publication))
+			return nil, apierror.FromDB(err, "cannot check if %s is member of publication %s", name, publication)
+		}
+		if isMember {
+			members = append(members, items[i])
+		} else {
+			log.Info(fmt.Sprintf("%s is not member of publication %s, skipping", name, publication))
+		}
+	}
+	return members, nil
+}
+
 func (pc *PublicationController) dropPublication(ctx context.Context, request CommonRequest) error {
 	log := utils.ContextLogger(ctx)
 
@@ -261,22 +633,25 @@ func (pc *PublicationController) dropPublication(ctx context.Context, request Co
 	}
 
 	log.Info(fmt.Sprintf("Publication %s drop started for database %s", publication, database))
-	if !pc.isPublicationExists(ctx, publication, database) {
+	exists, err := pc.isPublicationExists(ctx, publication, database)
+	if err != nil {
+		return err
+	}
+	if !exists {
 		log.Info(fmt.Sprintf("Publication %s doesn't exist in database %s", publication, database))
 		return nil
 	}
 
 	conn, err := pc.pgClient.GetConnectionToDb(ctx, database)
 	if err != nil {
-		panic(err)
+		return apierror.FromConnection(err, "cannot connect to database %s", database)
 	}
 	defer conn.Close(ctx)
 
-	log.Debug(getPubDropQuery(publication))
-	_, err = conn.Exec(ctx, getPubDropQuery(publication))
+	err = execStatements(ctx, conn, getPubDropQuery(publication))
 	if err != nil {
-		log.Error(fmt.Sprintf("cannot drop publication %s for database %s", publication, database))
-		panic(err)
+		log.Error(fmt.Sprintf("cannot drop publication %s for database %s", publication, database), zap.Error(err))
+		return apierror.FromDB(err, "cannot drop publication %s for database %s", publication, database)
 	}
 	log.Info(fmt.Sprintf("Publication %s has been dropped for database %s", publication, database))
 	return nil
@@ -308,21 +683,161 @@ func processTableRows(rows pgx.Rows) (map[string][]Table, error) {
 	return tablesInfo, nil
 }
 
-func (pc *PublicationController) isPublicationExists(ctx context.Context, publication, database string) bool {
+func scanPublication(rows pgx.Rows, pubInfo *PublicationInfo) error {
+	var pubInsert, pubUpdate, pubDelete, pubTruncate, pubViaRoot bool
+	err := rows.Scan(&pubInfo.Name, &pubInfo.Owner, &pubInsert, &pubUpdate, &pubDelete, &pubTruncate, &pubViaRoot)
+	if err != nil {
+		return err
+	}
+	publish := make([]string, 0, len(publishActions))
+	for i, enabled := range []bool{pubInsert, pubUpdate, pubDelete, pubTruncate} {
+		if enabled {
+			publish = append(publish, publishActions[i])
+		}
+	}
+	pubInfo.Options = PublicationOptions{
+		Publish:                 publish,
+		PublishViaPartitionRoot: &pubViaRoot,
+	}
+	return nil
+}
+
+func (pc *PublicationController) isPublicationExists(ctx context.Context, publication, database string) (bool, error) {
 	_, err := pc.getPublicationInternal(ctx, publication, database, false)
-	return err != isNotFoundErr
+	if apierror.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
 }
 
 func validatePublication(publication, database string) error {
 	if len(database) == 0 {
-		return fmt.Errorf("database must not be empty")
+		return apierror.BadRequest("database must not be empty")
 	}
 	if len(publication) == 0 {
-		return fmt.Errorf("database must not be empty")
+		return apierror.BadRequest("publication must not be empty")
+	}
+	return nil
+}
+
+// Validates request of publication create and alter
+func validateChangeRequest(request CommonRequest) error {
+	err := validatePublication(request.PubName, request.Database)
+	if err != nil {
+		return err
+	}
+	err = validatePublicationOptions(request.Options)
+	if err != nil {
+		return err
+	}
+	err = validateTables(request.Tables)
+	if err != nil {
+		return err
+	}
+	return identity.ValidateMode(request.IdentityMode)
+}
+
+// DROP TABLE accepts only table names, so column lists and row filters of entries are refused
+func validateDropRequest(request CommonRequest) error {
+	err := validatePublication(request.PubName, request.Database)
+	if err != nil {
+		return err
+	}
+	err = validateTables(request.Tables)
+	if err != nil {
+		return err
+	}
+	for _, table := range request.Tables {
+		if !table.isPlain() && (len(table.Columns) > 0 || len(table.Where) > 0) {
+			return apierror.BadRequest("columns and row filter of table %s cannot be dropped from publication, drop the table itself", table.Name)
+		}
+	}
+	return nil
+}
+
+func validatePublicationOptions(options *PublicationOptions) error {
+	if options == nil {
+		return nil
+	}
+	for _, action := range options.Publish {
+		if !slices.Contains(publishActions, action) {
+			return apierror.BadRequest("publish action %s is not supported, allowed actions: %s", action, strings.Join(publishActions, ", "))
+		}
 	}
 	return nil
 }
 
+func validateTables(tables []TableEntry) error {
+	for _, table := range tables {
+		if table.isPlain() {
+			continue
+		}
+		if len(table.Name) == 0 {
+			return apierror.BadRequest("table name must not be empty")
+		}
+		for _, column := range table.Columns {
+			if len(column) == 0 {
+				return apierror.BadRequest("column name of table %s must not be empty", table.Name)
+			}
+		}
+		if len(table.Where) > 0 {
+			err := validateRowFilter(table.Where)
+			if err != nil {
+				return apierror.BadRequest("row filter of table %s is invalid: %s", table.Name, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// Row filter is a single expression, so it must not be able to close
+// the WHERE clause or start another statement. Escape strings and dollar quoting
+// are refused, as their end cannot be found without full SQL lexer
+func validateRowFilter(expr string) error {
+	depth := 0
+	var quote rune
+	prev, beforePrev := rune(0), rune(0)
+	for _, ch := range expr {
+		switch {
+		case ch == '\\':
+			return fmt.Errorf("backslash is not allowed")
+		case ch == '$':
+			return fmt.Errorf("dollar sign is not allowed")
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' && (prev == 'e' || prev == 'E') && !isIdentifierChar(beforePrev):
+			return fmt.Errorf("escape string constants are not allowed")
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unbalanced parentheses")
+			}
+		case ch == ';':
+			return fmt.Errorf("semicolon is not allowed")
+		case (ch == '-' && prev == '-') || (ch == '*' && prev == '/'):
+			return fmt.Errorf("comments are not allowed")
+		}
+		prev, beforePrev = ch, prev
+	}
+	if quote != 0 {
+		return fmt.Errorf("unterminated quoted string")
+	}
+	if depth != 0 {
+		return fmt.Errorf("unbalanced parentheses")
+	}
+	return nil
+}
+
+func isIdentifierChar(ch rune) bool {
+	return ch == '_' || unicode.IsLetter(ch) || unicode.IsDigit(ch)
+}
+
 func convAttrStrToSlice(attrs string) []string {
 	attrs = strings.Trim(attrs, "{")
 	attrs = strings.Trim(attrs, "}")
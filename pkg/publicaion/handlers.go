@@ -16,36 +16,128 @@ package publication
 
 import (
 	"context"
-	"fmt"
-	"strconv"
+	"encoding/json"
 
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
 	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/log"
-	"go.uber.org/zap"
 )
 
 type CommonRequest struct {
-	PubName  string   `json:"publicationName"`
-	Database string   `json:"database"`
-	Tables   []string `json:"tables,omitempty"`
-	Schemas  []string `json:"schemas,omitempty"`
+	PubName  string              `json:"publicationName"`
+	Database string              `json:"database"`
+	Tables   []TableEntry        `json:"tables,omitempty"`
+	Schemas  []string            `json:"schemas,omitempty"`
+	Options  *PublicationOptions `json:"options,omitempty"`
+	// IdentityMode is one of ignore, refuse or fix for tables breaking UPDATE and DELETE replication
+	IdentityMode string `json:"replicaIdentityMode,omitempty"`
+}
+
+// TableEntry is either plain string "schema.table(col1,col2)" or
+// structured object {schema, name, columns, where}
+type TableEntry struct {
+	Schema  string   `json:"schema,omitempty"`
+	Name    string   `json:"name"`
+	Columns []string `json:"columns,omitempty"`
+	Where   string   `json:"where,omitempty"`
+
+	plain string
+}
+
+// PublicationSpec is a full desired definition of publication,
+// empty tables and schemas without allTables mean publication without tables
+type PublicationSpec struct {
+	AllTables bool                `json:"allTables,omitempty"`
+	Tables    []TableEntry        `json:"tables,omitempty"`
+	Schemas   []string            `json:"schemas,omitempty"`
+	Options   *PublicationOptions `json:"options,omitempty"`
+	Owner     string              `json:"owner,omitempty"`
+}
+
+type ListRequest struct {
+	Database string
+	Owner    string
+	Prefix   string
+}
+
+func (t *TableEntry) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*t = TableEntry{}
+		return json.Unmarshal(data, &t.plain)
+	}
+	type structuredTable TableEntry
+	var table structuredTable
+	err := json.Unmarshal(data, &table)
+	if err != nil {
+		return err
+	}
+	*t = TableEntry(table)
+	return nil
+}
+
+func (t TableEntry) MarshalJSON() ([]byte, error) {
+	if t.isPlain() {
+		return json.Marshal(t.plain)
+	}
+	type structuredTable TableEntry
+	return json.Marshal(structuredTable(t))
+}
+
+func (t TableEntry) String() string {
+	if t.isPlain() {
+		return t.plain
+	}
+	return prepareStructuredTable(t)
+}
+
+func (t TableEntry) isPlain() bool {
+	return len(t.plain) > 0
 }
 
 func (pc *PublicationController) PublicationCreateHandler(c *fiber.Ctx) error {
-	return handleCommonFunc(c, pc.createPublication)
+	return handleCommonFunc(c, pc.createPublication, pc.planCreatePublication)
 }
 
 func (pc *PublicationController) PublicationAlterAddHandler(c *fiber.Ctx) error {
-	return handleCommonFunc(c, pc.alterAddPublication)
+	return handleCommonFunc(c, pc.alterAddPublication, pc.planAlterAddPublication)
 }
 
 func (pc *PublicationController) PublicationAlterSetHandler(c *fiber.Ctx) error {
-	return handleCommonFunc(c, pc.alterSetPublication)
+	return handleCommonFunc(c, pc.alterSetPublication, pc.planAlterSetPublication)
+}
+
+func (pc *PublicationController) PublicationAlterDropHandler(c *fiber.Ctx) error {
+	return handleCommonFunc(c, pc.alterDropPublication, planAlterDropPublication)
 }
 
 func (pc *PublicationController) PublicationDropHandler(c *fiber.Ctx) error {
-	return handleCommonFunc(c, pc.dropPublication)
+	return handleCommonFunc(c, pc.dropPublication, planDropPublication)
+}
+
+func (pc *PublicationController) PublicationApplyHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
+	var spec PublicationSpec
+	if len(c.Body()) > 0 {
+		err := c.BodyParser(&spec)
+		if err != nil {
+			return apierror.BadRequest("cannot parse request: %s", err.Error())
+		}
+	}
+
+	dryRun, err := utils.GetQueryBoolParam(c, "dryRun")
+	if err != nil {
+		return err
+	}
+	allowRecreate, err := utils.GetQueryBoolParam(c, "allowRecreate")
+	if err != nil {
+		return err
+	}
+
+	result, err := pc.applyPublication(ctx, c.Params("database"), c.Params("publication"), spec, dryRun, allowRecreate)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(result)
 }
 
 func (pc *PublicationController) PublicationGetHandler(c *fiber.Ctx) error {
@@ -54,32 +146,81 @@ func (pc *PublicationController) PublicationGetHandler(c *fiber.Ctx) error {
 		PubName:  c.Params("publication"),
 	}
 
-	withTables, err := getQueryBoolParam(c, "withTables")
+	ctx := utils.GetRequestContext(c)
+	withTables, err := utils.GetQueryBoolParam(c, "withTables")
 	if err != nil {
-		return badReq(c, err)
+		return err
 	}
 
-	ctx := utils.GetRequestContext(c)
 	pubInfo, err := pc.getPublication(ctx, request, withTables)
 	if err != nil {
-		if err == isNotFoundErr {
-			return c.SendStatus(fiber.StatusNotFound)
-		}
-		return badReq(c, err)
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(pubInfo)
 }
 
-func handleCommonFunc(c *fiber.Ctx, handleFunc func(context.Context, CommonRequest) error) error {
-	request, err := getCommonReq(c)
+func (pc *PublicationController) PublicationListHandler(c *fiber.Ctx) error {
+	request := ListRequest{
+		Database: c.Params("database"),
+		Owner:    c.Query("owner"),
+		Prefix:   c.Query("prefix"),
+	}
+
+	ctx := utils.GetRequestContext(c)
+	withTables, err := utils.GetQueryBoolParam(c, "withTables")
+	if err != nil {
+		return err
+	}
+
+	publications, err := pc.listPublications(ctx, request, withTables)
 	if err != nil {
 		return err
 	}
+
+	return c.Status(fiber.StatusOK).JSON(publications)
+}
+
+func (pc *PublicationController) PublicationInventoryHandler(c *fiber.Ctx) error {
+	request := ListRequest{
+		Owner:  c.Query("owner"),
+		Prefix: c.Query("prefix"),
+	}
+
 	ctx := utils.GetRequestContext(c)
+	withTables, err := utils.GetQueryBoolParam(c, "withTables")
+	if err != nil {
+		return err
+	}
+
+	inventory, err := pc.listClusterPublications(ctx, request, withTables)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(inventory)
+}
+
+// With dryRun query parameter the plan of request is returned instead of execution
+func handleCommonFunc(c *fiber.Ctx, handleFunc func(context.Context, CommonRequest) error, planFunc func(context.Context, CommonRequest) (Plan, error)) error {
+	ctx := utils.GetRequestContext(c)
+	request, err := getCommonReq(c)
+	if err != nil {
+		return err
+	}
+	dryRun, err := utils.GetQueryBoolParam(c, "dryRun")
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		plan, err := planFunc(ctx, request)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(plan)
+	}
 	err = handleFunc(ctx, request)
 	if err != nil {
-		return badReq(c, err)
+		return err
 	}
 	return ok(c)
 }
@@ -89,26 +230,12 @@ func getCommonReq(c *fiber.Ctx) (CommonRequest, error) {
 	if len(c.Body()) > 0 {
 		err := c.BodyParser(&request)
 		if err != nil {
-			return request, err
+			return request, apierror.BadRequest("cannot parse request: %s", err.Error())
 		}
 	}
 	return request, nil
 }
 
-func badReq(c *fiber.Ctx, err error) error {
-	return c.Status(fiber.StatusBadRequest).SendString(err.Error())
-}
-
 func ok(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).SendString("OK")
 }
-
-func getQueryBoolParam(c *fiber.Ctx, param string) (bool, error) {
-	paramStr := c.Query(param, "false")
-	boolVal, err := strconv.ParseBool(paramStr)
-	if err != nil {
-		log.Error(fmt.Sprintf("cannot parse bool value for param %s", param), zap.Error(err))
-		return false, err
-	}
-	return boolVal, nil
-}
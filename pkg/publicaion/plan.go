@@ -0,0 +1,131 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publication
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/identity"
+)
+
+// Plan is SQL which would be executed for request along with checks
+// performed before execution, it is formed without changes in database.
+// Replica identity of tables is read in fix mode to plan its statements
+type Plan struct {
+	Statements []string `json:"statements"`
+	Checks     []string `json:"checks"`
+}
+
+func (pc *PublicationController) planCreatePublication(ctx context.Context, request CommonRequest) (Plan, error) {
+	err := validateChangeRequest(request)
+	if err != nil {
+		return Plan{}, err
+	}
+	publication := request.PubName
+	allTables := len(request.Tables) == 0 && len(request.Schemas) == 0
+	checks := []string{fmt.Sprintf("publication %s doesn't exist in database %s, otherwise nothing is executed", publication, request.Database)}
+	if preflightEnabled {
+		checks = append(checks, "replication readiness checks are executed in advisory mode")
+	}
+	checks = append(checks, planReplicaIdentityChecks(request, allTables)...)
+
+	fixQueries, err := pc.planReplicaIdentityFixes(ctx, request, allTables)
+	if err != nil {
+		return Plan{}, err
+	}
+	return Plan{Statements: getCreateStatements(request, fixQueries), Checks: checks}, nil
+}
+
+func (pc *PublicationController) planAlterAddPublication(ctx context.Context, request CommonRequest) (Plan, error) {
+	return pc.planAlterPublication(ctx, request, getPubAlterAddQuery)
+}
+
+func (pc *PublicationController) planAlterSetPublication(ctx context.Context, request CommonRequest) (Plan, error) {
+	return pc.planAlterPublication(ctx, request, getPubAlterSetQuery)
+}
+
+func (pc *PublicationController) planAlterPublication(ctx context.Context, request CommonRequest, alterQuery func(string, []TableEntry, []string) string) (Plan, error) {
+	err := validateChangeRequest(request)
+	if err != nil {
+		return Plan{}, err
+	}
+	publication := request.PubName
+	if isEmptyAlterRequest(request) {
+		return Plan{}, apierror.BadRequest("nothing to add to publication %s in database %s", publication, request.Database)
+	}
+	checks := []string{fmt.Sprintf("publication %s exists in database %s", publication, request.Database)}
+	checks = append(checks, planReplicaIdentityChecks(request, false)...)
+
+	fixQueries, err := pc.planReplicaIdentityFixes(ctx, request, false)
+	if err != nil {
+		return Plan{}, err
+	}
+	return Plan{Statements: getAlterStatements(request, fixQueries, alterQuery), Checks: checks}, nil
+}
+
+func planAlterDropPublication(ctx context.Context, request CommonRequest) (Plan, error) {
+	err := validateDropRequest(request)
+	if err != nil {
+		return Plan{}, err
+	}
+	publication := request.PubName
+	if len(request.Tables) == 0 && len(request.Schemas) == 0 {
+		return Plan{}, apierror.BadRequest("nothing to drop from publication %s in database %s", publication, request.Database)
+	}
+	checks := []string{
+		fmt.Sprintf("publication %s exists in database %s", publication, request.Database),
+		"tables and schemas which are not members of publication are excluded from statement",
+	}
+	query := getPubAlterDropQuery(publication, trimTablesArgs(request.Tables), request.Schemas)
+	return Plan{Statements: []string{query}, Checks: checks}, nil
+}
+
+func planDropPublication(ctx context.Context, request CommonRequest) (Plan, error) {
+	err := validatePublication(request.PubName, request.Database)
+	if err != nil {
+		return Plan{}, err
+	}
+	checks := []string{fmt.Sprintf("publication %s exists in database %s, otherwise nothing is executed", request.PubName, request.Database)}
+	return Plan{Statements: []string{getPubDropQuery(request.PubName)}, Checks: checks}, nil
+}
+
+// Tables without replica identity are found by the same check as on execution
+func (pc *PublicationController) planReplicaIdentityFixes(ctx context.Context, request CommonRequest, allTables bool) ([]string, error) {
+	if getIdentityMode(request) != identity.ModeFix {
+		return nil, nil
+	}
+	conn, err := pc.pgClient.GetConnectionToDb(ctx, request.Database)
+	if err != nil {
+		return nil, apierror.FromConnection(err, "cannot connect to database %s", request.Database)
+	}
+	defer conn.Close(ctx)
+	return checkReplicaIdentity(ctx, conn, request, allTables)
+}
+
+func planReplicaIdentityChecks(request CommonRequest, allTables bool) []string {
+	mode := getIdentityMode(request)
+	if !publishesChanges(request.Options) || (!allTables && len(request.Tables) == 0 && len(request.Schemas) == 0) {
+		return nil
+	}
+	switch mode {
+	case identity.ModeRefuse:
+		return []string{"request is refused if tables have neither primary key nor replica identity"}
+	case identity.ModeFix:
+		return []string{"REPLICA IDENTITY FULL is set in the same transaction for tables without primary key or replica identity"}
+	}
+	return nil
+}
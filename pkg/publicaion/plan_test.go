@@ -0,0 +1,61 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publication
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/identity"
+)
+
+func TestStatementsStartWithIdentityFixes(t *testing.T) {
+	fixQueries := identity.GetFixQueries([]identity.TableIdentity{{Schema: "public", Name: "events"}})
+	request := CommonRequest{PubName: "pub", Database: "db", Tables: []TableEntry{{Schema: "public", Name: "events"}}}
+
+	cases := map[string][]string{
+		"create": getCreateStatements(request, fixQueries),
+		"add":    getAlterStatements(request, fixQueries, getPubAlterAddQuery),
+		"set":    getAlterStatements(request, fixQueries, getPubAlterSetQuery),
+	}
+	for name, statements := range cases {
+		if len(statements) != 2 || statements[0] != fixQueries[0] {
+			t.Errorf("%s statements don't start with replica identity fix: %v", name, statements)
+		}
+	}
+	if !slices.Equal(getCreateStatements(request, nil), []string{getPubCreateQuery("pub", request.Tables, nil, nil)}) {
+		t.Errorf("create statements without fixes: %v", getCreateStatements(request, nil))
+	}
+}
+
+func TestValidateDropRequest(t *testing.T) {
+	cases := []struct {
+		name  string
+		table TableEntry
+		valid bool
+	}{
+		{name: "structured", table: TableEntry{Schema: "public", Name: "events"}, valid: true},
+		{name: "plain", table: TableEntry{plain: "public.events"}, valid: true},
+		{name: "empty name", table: TableEntry{Schema: "public"}},
+		{name: "columns", table: TableEntry{Name: "events", Columns: []string{"id"}}},
+		{name: "row filter", table: TableEntry{Name: "events", Where: "id > 1"}},
+	}
+	for _, tc := range cases {
+		err := validateDropRequest(CommonRequest{PubName: "pub", Database: "db", Tables: []TableEntry{tc.table}})
+		if (err == nil) != tc.valid {
+			t.Errorf("%s: unexpected validation result %v", tc.name, err)
+		}
+	}
+}
@@ -19,20 +19,41 @@ import (
 	"strings"
 
 	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+	"github.com/jackc/pgx/v4"
 )
 
 const (
-	pubGetQuery                 = "select pubname as name, pubowner::regrole as owner from pg_publication where pubname=$1"
+	pubGetQuery                 = "select pubname as name, pubowner::regrole as owner, pubinsert, pubupdate, pubdelete, pubtruncate, pubviaroot from pg_publication where pubname=$1"
 	pubGetTablesQuery           = "select schemaname, tablename, attnames::TEXT, Coalesce(rowfilter,'') from pg_publication_tables where pubname=$1"
-	pubCreateAllTablesQuery     = "CREATE publication \"%s\" FOR ALL TABLES;"
+	pubListQuery                = "select pubname as name, pubowner::regrole as owner, pubinsert, pubupdate, pubdelete, pubtruncate, pubviaroot from pg_publication where ($1 = '' or pg_get_userbyid(pubowner) = $1) and ($2 = '' or starts_with(pubname, $2)) order by pubname"
+	pubHasTableQuery            = "select exists(select 1 from pg_publication_rel pr join pg_publication p on p.oid = pr.prpubid where p.pubname=$1 and pr.prrelid = to_regclass($2))"
+	pubHasSchemaQuery           = "select exists(select 1 from pg_publication_namespace pn join pg_publication p on p.oid = pn.pnpubid where p.pubname=$1 and pn.pnnspid = to_regnamespace($2))"
+	databasesListQuery          = "select datname from pg_database where not datistemplate and datallowconn order by datname"
+	pubCreateAllTablesQuery     = "CREATE publication \"%s\" FOR ALL TABLES"
 	pubCreateWithTablesQuery    = "CREATE publication \"%s\" FOR TABLE %s"
 	pubCreateWithSchemasQuery   = "CREATE publication \"%s\" FOR TABLES IN SCHEMA %s"
 	pubAlterAddWithTablesQuery  = "ALTER PUBLICATION \"%s\" ADD TABLE %s"
 	pubAlterAddWithSchemasQuery = "ALTER PUBLICATION \"%s\" ADD TABLES IN SCHEMA %s"
 	pubAlterSetWithTablesQuery  = "ALTER PUBLICATION \"%s\" SET TABLE %s"
+	pubAlterDropTablesQuery     = "ALTER PUBLICATION \"%s\" DROP TABLE %s"
+	pubAlterDropSchemasQuery    = "ALTER PUBLICATION \"%s\" DROP TABLES IN SCHEMA %s"
+	pubAlterSetOptionsQuery     = "ALTER PUBLICATION \"%s\" SET (%s)"
 	pubDropQuery                = "DROP publication \"%s\";"
+	pubCreateEmptyQuery         = "CREATE publication \"%s\""
+	pubAlterOwnerQuery          = "ALTER PUBLICATION \"%s\" OWNER TO %s"
+	pubStateQuery               = "select puballtables, pg_get_userbyid(pubowner), pubinsert, pubupdate, pubdelete, pubtruncate, pubviaroot from pg_publication where pubname=$1"
+	pubRelsQuery                = "select n.nspname, c.relname, coalesce((select array_agg(a.attname::text order by a.attnum) from pg_attribute a where a.attrelid = pr.prrelid and a.attnum = any(pr.prattrs)), '{}'), coalesce(pg_get_expr(pr.prqual, pr.prrelid), '') from pg_publication_rel pr join pg_publication p on p.oid = pr.prpubid join pg_class c on c.oid = pr.prrelid join pg_namespace n on n.oid = c.relnamespace where p.pubname=$1"
+	pubNamespacesQuery          = "select n.nspname from pg_publication_namespace pn join pg_publication p on p.oid = pn.pnpubid join pg_namespace n on n.oid = pn.pnnspid where p.pubname=$1"
+	resolveTableQuery           = "select n.nspname, c.relname from pg_class c join pg_namespace n on n.oid = c.relnamespace where c.oid = to_regclass($1)"
+
+	// Publication is never committed, it is used to deparse row filters
+	scratchPublication = "replication_controller_row_filters"
 
 	schemasAppend = "TABLES IN SCHEMA"
+	whereKeyword  = "WHERE"
+
+	publishOption                 = "publish"
+	publishViaPartitionRootOption = "publish_via_partition_root"
 )
 
 func getPubGetQuery() string {
@@ -43,27 +64,83 @@ func getPubGetTablesQuery() string {
 	return pubGetTablesQuery
 }
 
-func getPubCreateAllTablesQuery(publication string) string {
-	return fmt.Sprintf(pubCreateAllTablesQuery, postgres.EscapeInputValue(publication))
+func getPubListQuery() string {
+	return pubListQuery
+}
+
+func getPubHasTableQuery() string {
+	return pubHasTableQuery
+}
+
+func getPubHasSchemaQuery() string {
+	return pubHasSchemaQuery
+}
+
+func getPubStateQuery() string {
+	return pubStateQuery
+}
+
+func getPubRelsQuery() string {
+	return pubRelsQuery
+}
+
+func getPubNamespacesQuery() string {
+	return pubNamespacesQuery
+}
+
+func getResolveTableQuery() string {
+	return resolveTableQuery
 }
 
-func getPubCreateQuery(publication string, tables, schemas []string) string {
-	return formQueryWithTablesAndSchemas(publication, tables, schemas, pubCreateWithTablesQuery, pubCreateWithSchemasQuery)
+func getDatabasesListQuery() string {
+	return databasesListQuery
 }
 
-func getPubAlterAddQuery(publication string, tables, schemas []string) string {
+func getPubCreateAllTablesQuery(publication string, options *PublicationOptions) string {
+	query := fmt.Sprintf(pubCreateAllTablesQuery, postgres.EscapeInputValue(publication))
+	return appendWithClause(query, options)
+}
+
+func getPubCreateEmptyQuery(publication string, options *PublicationOptions) string {
+	query := fmt.Sprintf(pubCreateEmptyQuery, postgres.EscapeInputValue(publication))
+	return appendWithClause(query, options)
+}
+
+func getPubCreateQuery(publication string, tables []TableEntry, schemas []string, options *PublicationOptions) string {
+	query := formQueryWithTablesAndSchemas(publication, tables, schemas, pubCreateWithTablesQuery, pubCreateWithSchemasQuery)
+	return appendWithClause(query, options)
+}
+
+// Returns empty string if there are no options to set
+func getPubAlterOptionsQuery(publication string, options *PublicationOptions) string {
+	params := formOptionsParams(options)
+	if len(params) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(pubAlterSetOptionsQuery, postgres.EscapeInputValue(publication), params)
+}
+
+func getPubAlterAddQuery(publication string, tables []TableEntry, schemas []string) string {
 	return formQueryWithTablesAndSchemas(publication, tables, schemas, pubAlterAddWithTablesQuery, pubAlterAddWithSchemasQuery)
 }
 
-func getPubAlterSetQuery(publication string, tables []string, schemas []string) string {
+func getPubAlterSetQuery(publication string, tables []TableEntry, schemas []string) string {
 	return formQueryWithTablesAndSchemas(publication, tables, schemas, pubAlterSetWithTablesQuery, pubAlterAddWithSchemasQuery)
 }
 
+func getPubAlterDropQuery(publication string, tables []TableEntry, schemas []string) string {
+	return formQueryWithTablesAndSchemas(publication, tables, schemas, pubAlterDropTablesQuery, pubAlterDropSchemasQuery)
+}
+
+func getPubAlterOwnerQuery(publication, owner string) string {
+	return fmt.Sprintf(pubAlterOwnerQuery, postgres.EscapeInputValue(publication), pgx.Identifier{owner}.Sanitize())
+}
+
 func getPubDropQuery(publication string) string {
 	return fmt.Sprintf(pubDropQuery, postgres.EscapeInputValue(publication))
 }
 
-func formQueryWithTablesAndSchemas(publication string, tables, schemas []string, queryForTables, queryForSchemas string) string {
+func formQueryWithTablesAndSchemas(publication string, tables []TableEntry, schemas []string, queryForTables, queryForSchemas string) string {
 	var query string
 	areTablesPresent := len(tables) > 0
 	areSchemasPresent := len(schemas) > 0
@@ -74,7 +151,7 @@ func formQueryWithTablesAndSchemas(publication string, tables, schemas []string,
 	if areSchemasPresent {
 		prepSchemas := prepareSchemas(schemas)
 		if areTablesPresent {
-			query = fmt.Sprintf("%s, %s %s;", query, schemasAppend, strings.Join(prepSchemas, ","))
+			query = fmt.Sprintf("%s, %s %s", query, schemasAppend, strings.Join(prepSchemas, ","))
 		} else {
 			query = fmt.Sprintf(queryForSchemas, postgres.EscapeInputValue(publication), strings.Join(prepSchemas, ","))
 		}
@@ -82,20 +159,50 @@ func formQueryWithTablesAndSchemas(publication string, tables, schemas []string,
 	return query
 }
 
-func prepareTables(tables []string) []string {
+func prepareTables(tables []TableEntry) []string {
 	preparedTables := make([]string, 0, len(tables))
 	for _, origTable := range tables {
-		table := postgres.EscapeInputValue(origTable)
-		if strings.Contains(table, ".") {
-			table = prepareTableWithSchema(table)
+		var table string
+		if origTable.isPlain() {
+			table = prepareTableString(origTable.plain)
 		} else {
-			table = prepareTableWithArgs(table)
+			table = prepareStructuredTable(origTable)
 		}
 		preparedTables = append(preparedTables, table)
 	}
 	return preparedTables
 }
 
+func prepareTableString(origTable string) string {
+	table := postgres.EscapeInputValue(origTable)
+	if strings.Contains(table, ".") {
+		table = prepareTableWithSchema(table)
+	} else {
+		table = prepareTableWithArgs(table)
+	}
+	return table
+}
+
+// Forms PG15+ clause: "schema"."table" ("col1", "col2") WHERE (expr)
+func prepareStructuredTable(table TableEntry) string {
+	identifier := pgx.Identifier{table.Name}
+	if len(table.Schema) > 0 {
+		identifier = pgx.Identifier{table.Schema, table.Name}
+	}
+	prepared := identifier.Sanitize()
+	if len(table.Columns) > 0 {
+		columns := make([]string, 0, len(table.Columns))
+		for _, column := range table.Columns {
+			columns = append(columns, pgx.Identifier{column}.Sanitize())
+		}
+		prepared = fmt.Sprintf("%s (%s)", prepared, strings.Join(columns, ", "))
+	}
+	if len(table.Where) > 0 {
+		prepared = fmt.Sprintf("%s WHERE (%s)", prepared, table.Where)
+	}
+	return prepared
+}
+
 func prepareTableWithSchema(table string) string {
 	tableArr := strings.Split(table, ".")
 	schema := fmt.Sprintf("\"%s\"", tableArr[0])
@@ -123,3 +230,49 @@ func prepareSchemas(schemas []string) []string {
 	}
 	return preparedSchemas
 }
+
+func appendWithClause(query string, options *PublicationOptions) string {
+	params := formOptionsParams(options)
+	if len(params) == 0 {
+		return query
+	}
+	return fmt.Sprintf("%s WITH (%s)", query, params)
+}
+
+func formOptionsParams(options *PublicationOptions) string {
+	if options == nil {
+		return ""
+	}
+	params := make([]string, 0, 2)
+	if options.Publish != nil {
+		params = append(params, fmt.Sprintf("%s = '%s'", publishOption, strings.Join(options.Publish, ", ")))
+	}
+	if options.PublishViaPartitionRoot != nil {
+		params = append(params, fmt.Sprintf("%s = %t", publishViaPartitionRootOption, *options.PublishViaPartitionRoot))
+	}
+	return strings.Join(params, ", ")
+}
+
+func nonEmptyQueries(queries ...string) []string {
+	nonEmpty := make([]string, 0, len(queries))
+	for _, query := range queries {
+		if len(query) > 0 {
+			nonEmpty = append(nonEmpty, query)
+		}
+	}
+	return nonEmpty
+}
+
+// DROP TABLE doesn't accept column lists and row filters
+func trimTablesArgs(tables []TableEntry) []TableEntry {
+	trimmed := make([]TableEntry, 0, len(tables))
+	for _, table := range tables {
+		if table.isPlain() {
+			plain, _, _ := strings.Cut(table.plain, "(")
+			trimmed = append(trimmed, TableEntry{plain: strings.TrimSpace(plain)})
+		} else {
+			trimmed = append(trimmed, TableEntry{Schema: table.Schema, Name: table.Name})
+		}
+	}
+	return trimmed
+}
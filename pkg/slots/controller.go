@@ -0,0 +1,318 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slots
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/audit"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPlugin = "pgoutput"
+)
+
+var (
+	supportedPlugins = []string{"pgoutput", "test_decoding"}
+	slotNameRegexp   = regexp.MustCompile("^[a-z0-9_]{1,63}$")
+
+	lagThresholdBytes = int64(utils.GetEnvInt("SLOT_LAG_THRESHOLD_BYTES", 1024*1024*1024))
+)
+
+type SlotController struct {
+	pgClient *postgres.Client
+}
+
+type SlotInfo struct {
+	Name              string `json:"slotName"`
+	Plugin            string `json:"plugin"`
+	Database          string `json:"database"`
+	Temporary         bool   `json:"temporary"`
+	TwoPhase          bool   `json:"twoPhase"`
+	Active            bool   `json:"active"`
+	ActivePid         *int32 `json:"activePid,omitempty"`
+	RestartLsn        string `json:"restartLsn,omitempty"`
+	ConfirmedFlushLsn string `json:"confirmedFlushLsn,omitempty"`
+}
+
+func NewSlotController(pgClient *postgres.Client) *SlotController {
+	return &SlotController{pgClient: pgClient}
+}
+
+func (sc *SlotController) listSlots(ctx context.Context, database string) ([]SlotInfo, error) {
+	log := utils.ContextLogger(ctx)
+
+	log.Info(fmt.Sprintf("List logical replication slots for database '%s'", database))
+	conn, err := sc.pgClient.GetConnection(ctx)
+	if err != nil {
+		return nil, apierror.FromConnection(err, "cannot connect to postgres")
+	}
+	defer conn.Close(ctx)
+
+	serverVersion, err := postgres.GetServerVersion(ctx, conn)
+	if err != nil {
+		log.Error("cannot get server version", zap.Error(err))
+		return nil, apierror.FromDB(err, "cannot get server version")
+	}
+	rows, err := conn.Query(ctx, getSlotListQuery(serverVersion), database)
+	if err != nil {
+		log.Error("cannot list logical replication slots")
+		return nil, apierror.FromDB(err, "cannot list logical replication slots")
+	}
+	defer rows.Close()
+
+	slots := make([]SlotInfo, 0)
+	for rows.Next() {
+		slot, err := scanSlot(rows)
+		if err != nil {
+			log.Error("cannot scan logical replication slots")
+			return nil, apierror.FromDB(err, "cannot scan logical replication slots")
+		}
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
+
+func (sc *SlotController) getSlotsLag(ctx context.Context, thresholdBytes int64) ([]postgres.SlotLag, error) {
+	log := utils.ContextLogger(ctx)
+
+	log.Info(fmt.Sprintf("Get replication slots lag with threshold %d bytes", thresholdBytes))
+	slots, err := sc.pgClient.RequestSlotsLag(ctx, thresholdBytes)
+	if err != nil {
+		log.Error("cannot get replication slots lag", zap.Error(err))
+		return nil, apierror.FromDB(err, "cannot get replication slots lag")
+	}
+	for _, slot := range slots {
+		if slot.ExceedsThreshold {
+			log.Warn(fmt.Sprintf("Replication slot %s exceeds threshold: retained %d bytes", slot.Name, slot.RetainedWalBytes))
+		}
+	}
+	return slots, nil
+}
+
+// Absent slot is reported as apierror with 404 status
+func (sc *SlotController) getSlotInternal(ctx context.Context, slotName string) (SlotInfo, error) {
+	log := utils.ContextLogger(ctx)
+
+	conn, err := sc.pgClient.GetConnection(ctx)
+	if err != nil {
+		return SlotInfo{}, apierror.FromConnection(err, "cannot connect to postgres")
+	}
+	defer conn.Close(ctx)
+
+	serverVersion, err := postgres.GetServerVersion(ctx, conn)
+	if err != nil {
+		log.Error("cannot get server version", zap.Error(err))
+		return SlotInfo{}, apierror.FromDB(err, "cannot get server version")
+	}
+	rows, err := conn.Query(ctx, getSlotGetQuery(serverVersion), slotName)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot get replication slot %s", slotName))
+		return SlotInfo{}, apierror.FromDB(err, "cannot get replication slot %s", slotName)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return SlotInfo{}, apierror.NotFound("replication slot %s doesn't exist", slotName)
+	}
+	slot, err := scanSlot(rows)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot scan replication slot %s", slotName))
+		return SlotInfo{}, apierror.FromDB(err, "cannot scan replication slot %s", slotName)
+	}
+	return slot, nil
+}
+
+func (sc *SlotController) createSlot(ctx context.Context, request SlotRequest) error {
+	log := utils.ContextLogger(ctx)
+
+	slotName := request.SlotName
+	database := request.Database
+	err := validateCreateRequest(request)
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return err
+	}
+	plugin := request.Plugin
+	if len(plugin) == 0 {
+		plugin = defaultPlugin
+	}
+
+	log.Info(fmt.Sprintf("Replication slot %s creation started for database %s", slotName, database))
+	slot, err := sc.getSlotInternal(ctx, slotName)
+	if err == nil {
+		if slot.Database != database || slot.Plugin != plugin {
+			err = apierror.Conflict("replication slot %s already exists for database %s with plugin %s", slotName, slot.Database, slot.Plugin)
+			log.Error(err.Error())
+			return err
+		}
+		log.Info(fmt.Sprintf("Replication slot %s already exists in database %s", slotName, database))
+		return nil
+	} else if !apierror.IsNotFound(err) {
+		return err
+	}
+
+	// Logical slot is bound to the database of connection
+	conn, err := sc.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	serverVersion, err := postgres.GetServerVersion(ctx, conn)
+	if err != nil {
+		log.Error("cannot get server version", zap.Error(err))
+		return apierror.FromDB(err, "cannot get server version")
+	}
+	args := []interface{}{slotName, plugin}
+	if serverVersion >= twoPhaseVersion {
+		args = append(args, request.TwoPhase)
+	} else if request.TwoPhase {
+		err = apierror.BadRequest("two phase replication slots are supported since PostgreSQL 14, server version is %d", serverVersion)
+		log.Error(err.Error())
+		return err
+	}
+
+	var lsn string
+	audit.AddStatement(ctx, getSlotCreateQuery(serverVersion), args...)
+	err = conn.QueryRow(ctx, getSlotCreateQuery(serverVersion), args...).Scan(&lsn)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot create replication slot %s for database %s", slotName, database), zap.Error(err))
+		return apierror.FromDB(err, "cannot create replication slot %s for database %s", slotName, database)
+	}
+
+	log.Info(fmt.Sprintf("Replication slot %s has been created for database %s at %s", slotName, database, lsn))
+	return nil
+}
+
+func (sc *SlotController) dropSlot(ctx context.Context, request SlotRequest) error {
+	log := utils.ContextLogger(ctx)
+
+	slotName := request.SlotName
+	err := validateSlotName(slotName)
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return err
+	}
+
+	log.Info(fmt.Sprintf("Replication slot %s drop started", slotName))
+	slot, err := sc.getSlotInternal(ctx, slotName)
+	if apierror.IsNotFound(err) {
+		log.Info(fmt.Sprintf("Replication slot %s doesn't exist", slotName))
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if slot.Active {
+		err = apierror.Conflict("replication slot %s is active and cannot be dropped", slotName)
+		log.Error(err.Error())
+		return err
+	}
+
+	conn, err := sc.pgClient.GetConnection(ctx)
+	if err != nil {
+		return apierror.FromConnection(err, "cannot connect to postgres")
+	}
+	defer conn.Close(ctx)
+
+	audit.AddStatement(ctx, getSlotDropQuery(), slotName)
+	_, err = conn.Exec(ctx, getSlotDropQuery(), slotName)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot drop replication slot %s", slotName), zap.Error(err))
+		return apierror.FromDB(err, "cannot drop replication slot %s", slotName)
+	}
+	log.Info(fmt.Sprintf("Replication slot %s has been dropped", slotName))
+	return nil
+}
+
+func (sc *SlotController) advanceSlot(ctx context.Context, request SlotRequest) error {
+	log := utils.ContextLogger(ctx)
+
+	slotName := request.SlotName
+	err := validateSlotName(slotName)
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return err
+	}
+	if len(request.Lsn) == 0 {
+		err = apierror.BadRequest("lsn must not be empty")
+		log.Error(err.Error(), zap.Error(err))
+		return err
+	}
+
+	log.Info(fmt.Sprintf("Replication slot %s advance to %s started", slotName, request.Lsn))
+	slot, err := sc.getSlotInternal(ctx, slotName)
+	if err != nil {
+		log.Info(fmt.Sprintf("Replication slot %s cannot be get", slotName), zap.Error(err))
+		return err
+	}
+
+	// Logical slot can be advanced only from its own database
+	conn, err := sc.pgClient.GetConnectionToDb(ctx, slot.Database)
+	if err != nil {
+		return apierror.FromConnection(err, "cannot connect to database %s", slot.Database)
+	}
+	defer conn.Close(ctx)
+
+	var lsn string
+	audit.AddStatement(ctx, getSlotAdvanceQuery(), slotName, request.Lsn)
+	err = conn.QueryRow(ctx, getSlotAdvanceQuery(), slotName, request.Lsn).Scan(&lsn)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot advance replication slot %s", slotName), zap.Error(err))
+		return apierror.FromDB(err, "cannot advance replication slot %s", slotName)
+	}
+	log.Info(fmt.Sprintf("Replication slot %s has been advanced to %s", slotName, lsn))
+	return nil
+}
+
+func scanSlot(rows pgx.Rows) (SlotInfo, error) {
+	var slot SlotInfo
+	err := rows.Scan(&slot.Name, &slot.Plugin, &slot.Database, &slot.Temporary, &slot.TwoPhase,
+		&slot.Active, &slot.ActivePid, &slot.RestartLsn, &slot.ConfirmedFlushLsn)
+	return slot, err
+}
+
+func validateCreateRequest(request SlotRequest) error {
+	err := validateSlotName(request.SlotName)
+	if err != nil {
+		return err
+	}
+	if len(request.Database) == 0 {
+		return apierror.BadRequest("database must not be empty")
+	}
+	if len(request.Plugin) > 0 && !slices.Contains(supportedPlugins, request.Plugin) {
+		return apierror.BadRequest("plugin %s is not supported, allowed plugins: %s", request.Plugin, strings.Join(supportedPlugins, ", "))
+	}
+	return nil
+}
+
+func validateSlotName(slotName string) error {
+	if len(slotName) == 0 {
+		return apierror.BadRequest("slot name must not be empty")
+	}
+	if !slotNameRegexp.MatchString(slotName) {
+		return apierror.BadRequest("slot name may contain only lower case letters, numbers and underscore, up to 63 characters")
+	}
+	return nil
+}
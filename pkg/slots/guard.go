@@ -0,0 +1,302 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slots
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	PolicyLog   = "log"
+	PolicyEvent = "event"
+	PolicyDrop  = "drop"
+
+	guardRequestId = "slot-guard"
+	logicalSlot    = "logical"
+)
+
+var guardPolicies = []string{PolicyLog, PolicyEvent, PolicyDrop}
+
+type GuardConfig struct {
+	Interval         time.Duration
+	MaxInactive      time.Duration
+	MaxRetainedBytes int64
+	Policy           string
+	Protected        []string
+	ProtectedPattern string
+	EventUrl         string
+}
+
+// SlotGuard periodically looks for logical slots which are inactive for too long
+// or retain too much WAL and applies configured policy to them.
+// Inactivity is taken from inactive_since of PG17, for older servers start
+// of observed inactivity is kept in store to survive controller restarts
+type SlotGuard struct {
+	controller       *SlotController
+	config           GuardConfig
+	protectedPattern *regexp.Regexp
+	store            inactivityStore
+	inactiveSince    map[string]time.Time
+	loaded           bool
+	changed          bool
+	httpClient       *http.Client
+}
+
+// inactivityStore keeps observed start of slots inactivity
+type inactivityStore interface {
+	load(ctx context.Context) (map[string]time.Time, error)
+	save(ctx context.Context, inactiveSince map[string]time.Time) error
+}
+
+type guardEvent struct {
+	Action   string           `json:"action"`
+	Reason   string           `json:"reason"`
+	Slot     postgres.SlotLag `json:"slot"`
+	Inactive string           `json:"inactive,omitempty"`
+}
+
+func NewSlotGuard(pgClient *postgres.Client, config GuardConfig) (*SlotGuard, error) {
+	if !slices.Contains(guardPolicies, config.Policy) {
+		return nil, fmt.Errorf("slot guard policy %s is not supported, allowed policies: %v", config.Policy, guardPolicies)
+	}
+	if config.Policy == PolicyEvent && len(config.EventUrl) == 0 {
+		return nil, fmt.Errorf("slot guard event url must not be empty for policy %s", PolicyEvent)
+	}
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("slot guard interval must be positive")
+	}
+	guard := &SlotGuard{
+		controller:    NewSlotController(pgClient),
+		config:        config,
+		store:         newTableInactivityStore(pgClient),
+		inactiveSince: make(map[string]time.Time),
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+	}
+	if len(config.ProtectedPattern) > 0 {
+		pattern, err := regexp.Compile(config.ProtectedPattern)
+		if err != nil {
+			return nil, fmt.Errorf("slot guard protected pattern is invalid: %w", err)
+		}
+		guard.protectedPattern = pattern
+	}
+	return guard, nil
+}
+
+func (g *SlotGuard) Run(ctx context.Context) {
+	ctx = context.WithValue(ctx, utils.RequestId("request_id"), []byte(guardRequestId))
+	log := utils.ContextLogger(ctx)
+	log.Info(fmt.Sprintf("Slot guard started with policy %s, interval %s, max inactive %s, max retained %d bytes",
+		g.config.Policy, g.config.Interval, g.config.MaxInactive, g.config.MaxRetainedBytes))
+
+	ticker := time.NewTicker(g.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Slot guard stopped")
+			return
+		case <-ticker.C:
+			g.check(ctx)
+		}
+	}
+}
+
+func (g *SlotGuard) check(ctx context.Context) {
+	log := utils.ContextLogger(ctx)
+
+	// Zero max retained bytes disables threshold of slots lag as well
+	slots, err := g.controller.pgClient.RequestSlotsLag(ctx, g.config.MaxRetainedBytes)
+	if err != nil {
+		log.Error("Slot guard cannot get replication slots", zap.Error(err))
+		return
+	}
+	// Stored inactivity is merged before any slot is forgotten, so stale entries don't come back
+	g.loadInactivity(ctx)
+
+	now := time.Now()
+	seen := make(map[string]bool, len(slots))
+	for _, slot := range slots {
+		if slot.Type != logicalSlot {
+			continue
+		}
+		seen[slot.Name] = true
+		inactive := g.trackInactivity(ctx, slot, now)
+
+		reason := g.violation(slot, inactive)
+		if len(reason) == 0 {
+			continue
+		}
+		if g.isProtected(slot.Name) {
+			log.Info(fmt.Sprintf("Replication slot %s is protected, skipping", slot.Name),
+				zap.String("reason", reason), zap.Any("slot", slot))
+			continue
+		}
+		g.apply(ctx, guardEvent{Action: g.config.Policy, Reason: reason, Slot: slot, Inactive: formatInactive(inactive)})
+	}
+
+	// Forget slots which are gone
+	for name := range g.inactiveSince {
+		if !seen[name] {
+			g.forget(name)
+		}
+	}
+	g.persist(ctx)
+}
+
+// Returns how long slot is inactive, server reported inactive_since is preferred
+func (g *SlotGuard) trackInactivity(ctx context.Context, slot postgres.SlotLag, now time.Time) time.Duration {
+	if slot.Active {
+		g.forget(slot.Name)
+		return 0
+	}
+	if slot.InactiveSince != nil {
+		g.forget(slot.Name)
+		return now.Sub(*slot.InactiveSince)
+	}
+	g.loadInactivity(ctx)
+	since, ok := g.inactiveSince[slot.Name]
+	if !ok {
+		g.inactiveSince[slot.Name] = now
+		g.changed = true
+		return 0
+	}
+	return now.Sub(since)
+}
+
+// Stored inactivity is loaded once, it is tracked from now if store is unavailable
+func (g *SlotGuard) loadInactivity(ctx context.Context) {
+	if g.loaded {
+		return
+	}
+	inactiveSince, err := g.store.load(ctx)
+	if err != nil {
+		utils.ContextLogger(ctx).Warn("Slot guard cannot load stored slots inactivity", zap.Error(err))
+		return
+	}
+	for name, since := range inactiveSince {
+		if current, ok := g.inactiveSince[name]; !ok || since.Before(current) {
+			g.inactiveSince[name] = since
+		}
+	}
+	g.loaded = true
+}
+
+func (g *SlotGuard) forget(slotName string) {
+	if _, ok := g.inactiveSince[slotName]; ok {
+		delete(g.inactiveSince, slotName)
+		g.changed = true
+	}
+}
+
+// Stored inactivity is not overwritten until it has been loaded
+func (g *SlotGuard) persist(ctx context.Context) {
+	if !g.changed || !g.loaded {
+		return
+	}
+	if err := g.store.save(ctx, g.inactiveSince); err != nil {
+		utils.ContextLogger(ctx).Warn("Slot guard cannot store slots inactivity", zap.Error(err))
+		return
+	}
+	g.changed = false
+}
+
+func (g *SlotGuard) violation(slot postgres.SlotLag, inactive time.Duration) string {
+	if g.config.MaxInactive > 0 && inactive >= g.config.MaxInactive {
+		return fmt.Sprintf("inactive for %s", inactive.Round(time.Second))
+	}
+	if g.config.MaxRetainedBytes > 0 && slot.RetainedWalBytes >= g.config.MaxRetainedBytes {
+		return fmt.Sprintf("retains %d bytes of WAL", slot.RetainedWalBytes)
+	}
+	return ""
+}
+
+func (g *SlotGuard) isProtected(slotName string) bool {
+	if slices.Contains(g.config.Protected, slotName) {
+		return true
+	}
+	return g.protectedPattern != nil && g.protectedPattern.MatchString(slotName)
+}
+
+func (g *SlotGuard) apply(ctx context.Context, event guardEvent) {
+	log := utils.ContextLogger(ctx).With(
+		zap.String("action", event.Action),
+		zap.String("reason", event.Reason),
+		zap.Any("slot", event.Slot),
+	)
+	slotName := event.Slot.Name
+
+	switch event.Action {
+	case PolicyLog:
+		log.Warn(fmt.Sprintf("Replication slot %s violates slot guard policy", slotName))
+	case PolicyEvent:
+		err := g.sendEvent(ctx, event)
+		if err != nil {
+			log.Error(fmt.Sprintf("cannot send slot guard event for replication slot %s", slotName), zap.Error(err))
+			return
+		}
+		log.Warn(fmt.Sprintf("Slot guard event has been sent for replication slot %s", slotName))
+	case PolicyDrop:
+		if event.Slot.Active {
+			log.Warn(fmt.Sprintf("Replication slot %s is active and cannot be dropped by slot guard", slotName))
+			return
+		}
+		err := g.controller.dropSlot(ctx, SlotRequest{SlotName: slotName})
+		if err != nil {
+			log.Error(fmt.Sprintf("cannot drop replication slot %s by slot guard", slotName), zap.Error(err))
+			return
+		}
+		g.forget(slotName)
+		log.Warn(fmt.Sprintf("Replication slot %s has been dropped by slot guard", slotName))
+	}
+}
+
+func (g *SlotGuard) sendEvent(ctx context.Context, event guardEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, g.config.EventUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := g.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("event receiver responded with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+func formatInactive(inactive time.Duration) string {
+	if inactive == 0 {
+		return ""
+	}
+	return inactive.Round(time.Second).String()
+}
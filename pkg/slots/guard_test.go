@@ -0,0 +1,135 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slots
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+)
+
+type memoryInactivityStore struct {
+	inactiveSince map[string]time.Time
+}
+
+func (ms *memoryInactivityStore) load(ctx context.Context) (map[string]time.Time, error) {
+	return maps.Clone(ms.inactiveSince), nil
+}
+
+func (ms *memoryInactivityStore) save(ctx context.Context, inactiveSince map[string]time.Time) error {
+	ms.inactiveSince = maps.Clone(inactiveSince)
+	return nil
+}
+
+func newTestGuard(t *testing.T, store inactivityStore) *SlotGuard {
+	guard, err := NewSlotGuard(nil, GuardConfig{Interval: time.Minute, MaxInactive: time.Hour, Policy: PolicyLog})
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.store = store
+	return guard
+}
+
+func TestInactivitySurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryInactivityStore{}
+	slot := postgres.SlotLag{Name: "sub_slot", Type: logicalSlot}
+	start := time.Now()
+
+	guard := newTestGuard(t, store)
+	if inactive := guard.trackInactivity(ctx, slot, start); inactive != 0 {
+		t.Fatalf("inactivity of newly observed slot is %s", inactive)
+	}
+	guard.persist(ctx)
+
+	restarted := newTestGuard(t, store)
+	inactive := restarted.trackInactivity(ctx, slot, start.Add(2*time.Hour))
+	if inactive != 2*time.Hour {
+		t.Fatalf("inactivity after restart is %s, expected 2h", inactive)
+	}
+	if reason := restarted.violation(slot, inactive); len(reason) == 0 {
+		t.Fatal("slot inactive longer than max inactive is not reported")
+	}
+}
+
+func TestInactiveSinceOfServerIsPreferred(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryInactivityStore{}
+	now := time.Now()
+	since := now.Add(-3 * time.Hour)
+	slot := postgres.SlotLag{Name: "sub_slot", Type: logicalSlot, InactiveSince: &since}
+
+	guard := newTestGuard(t, store)
+	if inactive := guard.trackInactivity(ctx, slot, now); inactive != 3*time.Hour {
+		t.Fatalf("inactivity is %s, expected 3h", inactive)
+	}
+	guard.persist(ctx)
+	if len(store.inactiveSince) != 0 {
+		t.Fatalf("inactivity reported by server is stored: %v", store.inactiveSince)
+	}
+}
+
+func TestActiveSlotIsForgotten(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryInactivityStore{inactiveSince: map[string]time.Time{"sub_slot": time.Now().Add(-time.Hour)}}
+	slot := postgres.SlotLag{Name: "sub_slot", Type: logicalSlot, Active: true}
+
+	guard := newTestGuard(t, store)
+	guard.loadInactivity(ctx)
+	if inactive := guard.trackInactivity(ctx, slot, time.Now()); inactive != 0 {
+		t.Fatalf("inactivity of active slot is %s", inactive)
+	}
+	guard.persist(ctx)
+	if _, ok := store.inactiveSince["sub_slot"]; ok {
+		t.Fatal("active slot is kept in store")
+	}
+}
+
+type failingInactivityStore struct {
+	memoryInactivityStore
+	failLoad bool
+}
+
+func (fs *failingInactivityStore) load(ctx context.Context) (map[string]time.Time, error) {
+	if fs.failLoad {
+		return nil, errors.New("store is unavailable")
+	}
+	return fs.memoryInactivityStore.load(ctx)
+}
+
+func TestStoreIsNotOverwrittenBeforeLoad(t *testing.T) {
+	ctx := context.Background()
+	since := time.Now().Add(-time.Hour)
+	store := &failingInactivityStore{memoryInactivityStore{inactiveSince: map[string]time.Time{"old_slot": since}}, true}
+	slot := postgres.SlotLag{Name: "sub_slot", Type: logicalSlot}
+
+	guard := newTestGuard(t, store)
+	guard.trackInactivity(ctx, slot, time.Now())
+	guard.persist(ctx)
+	if _, ok := store.inactiveSince["old_slot"]; !ok || len(store.inactiveSince) != 1 {
+		t.Fatalf("store is overwritten while it cannot be loaded: %v", store.inactiveSince)
+	}
+
+	store.failLoad = false
+	guard.loadInactivity(ctx)
+	guard.persist(ctx)
+	if len(store.inactiveSince) != 2 {
+		t.Fatalf("store is not saved after load: %v", store.inactiveSince)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slots
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SlotRequest creates permanent slots only. Temporary slot is dropped with session,
+// which created it, and sessions of controller are pooled, so no client could use it
+type SlotRequest struct {
+	SlotName string `json:"slotName"`
+	Database string `json:"database,omitempty"`
+	Plugin   string `json:"plugin,omitempty"`
+	TwoPhase bool   `json:"twoPhase,omitempty"`
+	Lsn      string `json:"lsn,omitempty"`
+}
+
+func (sc *SlotController) SlotListHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
+	slots, err := sc.listSlots(ctx, c.Query("database"))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(slots)
+}
+
+func (sc *SlotController) SlotLagHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
+	thresholdBytes := lagThresholdBytes
+	if threshold := c.Query("thresholdBytes"); len(threshold) > 0 {
+		parsed, err := strconv.ParseInt(threshold, 10, 64)
+		if err != nil {
+			return apierror.BadRequest("cannot parse thresholdBytes value %s", threshold)
+		}
+		if parsed <= 0 {
+			return apierror.BadRequest("thresholdBytes must be positive, got %d", parsed)
+		}
+		thresholdBytes = parsed
+	}
+
+	slots, err := sc.getSlotsLag(ctx, thresholdBytes)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(slots)
+}
+
+func (sc *SlotController) SlotCreateHandler(c *fiber.Ctx) error {
+	return handleCommonFunc(c, sc.createSlot)
+}
+
+func (sc *SlotController) SlotDropHandler(c *fiber.Ctx) error {
+	return handleCommonFunc(c, sc.dropSlot)
+}
+
+func (sc *SlotController) SlotAdvanceHandler(c *fiber.Ctx) error {
+	return handleCommonFunc(c, sc.advanceSlot)
+}
+
+func handleCommonFunc(c *fiber.Ctx, handleFunc func(context.Context, SlotRequest) error) error {
+	ctx := utils.GetRequestContext(c)
+	request, err := getSlotReq(c)
+	if err != nil {
+		return err
+	}
+	err = handleFunc(ctx, request)
+	if err != nil {
+		return err
+	}
+	return ok(c)
+}
+
+func getSlotReq(c *fiber.Ctx) (SlotRequest, error) {
+	var request SlotRequest
+	if len(c.Body()) > 0 {
+		err := c.BodyParser(&request)
+		if err != nil {
+			return request, apierror.BadRequest("cannot parse request: %s", err.Error())
+		}
+	}
+	return request, nil
+}
+
+func ok(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).SendString("OK")
+}
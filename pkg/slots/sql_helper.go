@@ -0,0 +1,85 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slots
+
+import "fmt"
+
+const (
+	slotListQuery   = "select slot_name, coalesce(plugin, ''), coalesce(database, ''), temporary, %s, active, active_pid, coalesce(restart_lsn::text, ''), coalesce(confirmed_flush_lsn::text, '') from pg_replication_slots where slot_type = 'logical' and ($1 = '' or database = $1) order by slot_name"
+	slotGetQuery    = "select slot_name, coalesce(plugin, ''), coalesce(database, ''), temporary, %s, active, active_pid, coalesce(restart_lsn::text, ''), coalesce(confirmed_flush_lsn::text, '') from pg_replication_slots where slot_type = 'logical' and slot_name = $1"
+	slotCreateQuery = "select lsn::text from pg_create_logical_replication_slot($1, $2, false, $3)"
+	// Two phase slots exist since PG14, older servers have no twophase argument
+	slotCreateNoTwoPhaseQuery = "select lsn::text from pg_create_logical_replication_slot($1, $2, false)"
+	slotDropQuery             = "select pg_drop_replication_slot($1)"
+	slotAdvanceQuery          = "select end_lsn::text from pg_replication_slot_advance($1, $2::pg_lsn)"
+
+	twoPhaseColumn  = "two_phase"
+	twoPhaseAbsent  = "false"
+	twoPhaseVersion = 140000
+
+	controllerSchema       = "replication_controller"
+	inactivityPrepareQuery = "CREATE SCHEMA IF NOT EXISTS " + controllerSchema + ";\n" +
+		"CREATE TABLE IF NOT EXISTS " + controllerSchema + ".slot_inactivity (slot_name text primary key, inactive_since timestamptz not null)"
+	inactivitySelectQuery = "select slot_name, inactive_since from " + controllerSchema + ".slot_inactivity"
+	inactivityDeleteQuery = "delete from " + controllerSchema + ".slot_inactivity where slot_name <> all($1::text[])"
+	inactivityUpsertQuery = "insert into " + controllerSchema + ".slot_inactivity (slot_name, inactive_since) " +
+		"select * from unnest($1::text[], $2::timestamptz[]) on conflict (slot_name) do update set inactive_since = excluded.inactive_since"
+)
+
+func getSlotListQuery(serverVersion int) string {
+	return fmt.Sprintf(slotListQuery, getTwoPhaseColumn(serverVersion))
+}
+
+func getSlotGetQuery(serverVersion int) string {
+	return fmt.Sprintf(slotGetQuery, getTwoPhaseColumn(serverVersion))
+}
+
+func getTwoPhaseColumn(serverVersion int) string {
+	if serverVersion >= twoPhaseVersion {
+		return twoPhaseColumn
+	}
+	return twoPhaseAbsent
+}
+
+func getSlotCreateQuery(serverVersion int) string {
+	if serverVersion >= twoPhaseVersion {
+		return slotCreateQuery
+	}
+	return slotCreateNoTwoPhaseQuery
+}
+
+func getSlotDropQuery() string {
+	return slotDropQuery
+}
+
+func getSlotAdvanceQuery() string {
+	return slotAdvanceQuery
+}
+
+func getInactivityPrepareQuery() string {
+	return inactivityPrepareQuery
+}
+
+func getInactivitySelectQuery() string {
+	return inactivitySelectQuery
+}
+
+func getInactivityDeleteQuery() string {
+	return inactivityDeleteQuery
+}
+
+func getInactivityUpsertQuery() string {
+	return inactivityUpsertQuery
+}
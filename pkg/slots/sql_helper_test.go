@@ -0,0 +1,45 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slots
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlotQueriesTwoPhase(t *testing.T) {
+	cases := []struct {
+		version int
+		column  string
+		create  string
+	}{
+		{version: 130014, column: "false", create: "($1, $2, false)"},
+		{version: 140000, column: "two_phase", create: "($1, $2, false, $3)"},
+		{version: 170002, column: "two_phase", create: "($1, $2, false, $3)"},
+	}
+	for _, tc := range cases {
+		for name, query := range map[string]string{"list": getSlotListQuery(tc.version), "get": getSlotGetQuery(tc.version)} {
+			if !strings.Contains(query, "temporary, "+tc.column+", active") {
+				t.Errorf("%s query for version %d doesn't select %s: %s", name, tc.version, tc.column, query)
+			}
+			if tc.version < twoPhaseVersion && strings.Contains(query, "two_phase") {
+				t.Errorf("%s query for version %d selects two_phase: %s", name, tc.version, query)
+			}
+		}
+		if query := getSlotCreateQuery(tc.version); !strings.HasSuffix(query, tc.create) {
+			t.Errorf("create query for version %d doesn't end with %s: %s", tc.version, tc.create, query)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slots
+
+import (
+	"context"
+	"time"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+)
+
+// tableInactivityStore keeps slots inactivity in controller schema of default database
+type tableInactivityStore struct {
+	preparer *postgres.SchemaPreparer
+}
+
+func newTableInactivityStore(pgClient *postgres.Client) *tableInactivityStore {
+	return &tableInactivityStore{preparer: postgres.NewSchemaPreparer(pgClient, "slot inactivity table", getInactivityPrepareQuery())}
+}
+
+func (ts *tableInactivityStore) load(ctx context.Context) (map[string]time.Time, error) {
+	conn, err := ts.preparer.GetConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	rows, err := conn.Query(ctx, getInactivitySelectQuery())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inactiveSince := make(map[string]time.Time)
+	for rows.Next() {
+		var name string
+		var since time.Time
+		if err = rows.Scan(&name, &since); err != nil {
+			return nil, err
+		}
+		inactiveSince[name] = since
+	}
+	return inactiveSince, rows.Err()
+}
+
+func (ts *tableInactivityStore) save(ctx context.Context, inactiveSince map[string]time.Time) error {
+	conn, err := ts.preparer.GetConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	names := make([]string, 0, len(inactiveSince))
+	times := make([]time.Time, 0, len(inactiveSince))
+	for name, since := range inactiveSince {
+		names = append(names, name)
+		times = append(times, since)
+	}
+	if _, err = conn.Exec(ctx, getInactivityDeleteQuery(), names); err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, getInactivityUpsertQuery(), names, times)
+	return err
+}
@@ -0,0 +1,517 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subscriptions
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/audit"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+)
+
+var (
+	readyState = "r"
+	stateNames = map[string]string{
+		"i":        "init",
+		"d":        "data_copy",
+		"f":        "finished_copy",
+		"s":        "synchronized",
+		readyState: "ready",
+	}
+
+	streamingValues = []string{"on", "off", "parallel"}
+	originValues    = []string{"any", "none"}
+)
+
+// SubscriptionController manages subscriptions on subscriber cluster,
+// which may differ from the cluster of publications
+type SubscriptionController struct {
+	pgClient *postgres.Client
+}
+
+type SubscriptionInfo struct {
+	Name         string   `json:"name"`
+	Owner        string   `json:"owner"`
+	Database     string   `json:"database"`
+	Enabled      bool     `json:"enabled"`
+	Publications []string `json:"publications"`
+	SlotName     string   `json:"slotName,omitempty"`
+	Binary       bool     `json:"binary"`
+	Streaming    string   `json:"streaming"`
+	Origin       string   `json:"origin"`
+}
+
+type SubscriptionStatus struct {
+	Name         string         `json:"name"`
+	Database     string         `json:"database"`
+	Ready        bool           `json:"ready"`
+	States       map[string]int `json:"states"`
+	Tables       []TableState   `json:"tables"`
+	Workers      []WorkerInfo   `json:"workers"`
+	CopyProgress []CopyProgress `json:"copyProgress"`
+}
+
+type TableState struct {
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	State     string `json:"state"`
+	StateName string `json:"stateName"`
+	Lsn       string `json:"lsn,omitempty"`
+}
+
+type WorkerInfo struct {
+	Pid                *int32     `json:"pid,omitempty"`
+	Table              string     `json:"table,omitempty"`
+	ReceivedLsn        string     `json:"receivedLsn,omitempty"`
+	LatestEndLsn       string     `json:"latestEndLsn,omitempty"`
+	LastMsgReceiptTime *time.Time `json:"lastMsgReceiptTime,omitempty"`
+	LatestEndTime      *time.Time `json:"latestEndTime,omitempty"`
+}
+
+type CopyProgress struct {
+	Pid             int32  `json:"pid"`
+	Table           string `json:"table"`
+	BytesProcessed  int64  `json:"bytesProcessed"`
+	BytesTotal      int64  `json:"bytesTotal"`
+	TuplesProcessed int64  `json:"tuplesProcessed"`
+}
+
+type SubscriptionOptions struct {
+	Streaming string `json:"streaming,omitempty"`
+	Binary    *bool  `json:"binary,omitempty"`
+	Origin    string `json:"origin,omitempty"`
+
+	// Applicable only for creation
+	CopyData   *bool  `json:"copyData,omitempty"`
+	CreateSlot *bool  `json:"createSlot,omitempty"`
+	Enabled    *bool  `json:"enabled,omitempty"`
+	SlotName   string `json:"slotName,omitempty"`
+}
+
+func NewSubscriptionController(pgClient *postgres.Client) *SubscriptionController {
+	return &SubscriptionController{pgClient: pgClient}
+}
+
+// Absent database is reported as apierror with 404 status
+func (sc *SubscriptionController) listSubscriptions(ctx context.Context, database string) ([]SubscriptionInfo, error) {
+	log := utils.ContextLogger(ctx)
+
+	if len(database) == 0 {
+		err := apierror.BadRequest("database must not be empty")
+		log.Error(err.Error(), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info(fmt.Sprintf("List subscriptions for database %s", database))
+	conn, err := sc.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return nil, apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	version, err := postgres.GetServerVersion(ctx, conn)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot get server version for database %s", database))
+		return nil, apierror.FromDB(err, "cannot get server version for database %s", database)
+	}
+	rows, err := conn.Query(ctx, getSubListQuery(version))
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot list subscriptions for database %s", database))
+		return nil, apierror.FromDB(err, "cannot list subscriptions for database %s", database)
+	}
+	defer rows.Close()
+
+	subscriptions := make([]SubscriptionInfo, 0)
+	for rows.Next() {
+		subInfo := SubscriptionInfo{Database: database}
+		err = scanSubscription(rows, &subInfo)
+		if err != nil {
+			log.Error(fmt.Sprintf("cannot scan subscriptions for database %s", database))
+			return nil, apierror.FromDB(err, "cannot scan subscriptions for database %s", database)
+		}
+		subscriptions = append(subscriptions, subInfo)
+	}
+	return subscriptions, nil
+}
+
+// Absent database or subscription is reported as apierror with 404 status
+func (sc *SubscriptionController) getSubscriptionInternal(ctx context.Context, subscription, database string) (SubscriptionInfo, error) {
+	log := utils.ContextLogger(ctx)
+
+	conn, err := sc.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return SubscriptionInfo{}, apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	version, err := postgres.GetServerVersion(ctx, conn)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot get server version for database %s", database))
+		return SubscriptionInfo{}, apierror.FromDB(err, "cannot get server version for database %s", database)
+	}
+	rows, err := conn.Query(ctx, getSubGetQuery(version), subscription)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot get subscription %s for database %s", subscription, database))
+		return SubscriptionInfo{}, apierror.FromDB(err, "cannot get subscription %s for database %s", subscription, database)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return SubscriptionInfo{}, apierror.NotFound("subscription %s doesn't exist in database %s", subscription, database)
+	}
+	subInfo := SubscriptionInfo{Database: database}
+	err = scanSubscription(rows, &subInfo)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot scan subscription %s for database %s", subscription, database))
+		return SubscriptionInfo{}, apierror.FromDB(err, "cannot scan subscription %s for database %s", subscription, database)
+	}
+	return subInfo, nil
+}
+
+// Subscription is ready, when all of its tables are in ready state
+func (sc *SubscriptionController) getSubscriptionStatus(ctx context.Context, subscription, database string) (SubscriptionStatus, error) {
+	log := utils.ContextLogger(ctx)
+
+	err := validateSubscription(subscription, database)
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return SubscriptionStatus{}, err
+	}
+	_, err = sc.getSubscriptionInternal(ctx, subscription, database)
+	if err != nil {
+		log.Info(fmt.Sprintf("Subscription %s cannot be get in database %s", subscription, database), zap.Error(err))
+		return SubscriptionStatus{}, err
+	}
+
+	conn, err := sc.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return SubscriptionStatus{}, apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	status := SubscriptionStatus{
+		Name:     subscription,
+		Database: database,
+		Ready:    true,
+		States:   make(map[string]int),
+	}
+	status.Tables, err = getTableStates(ctx, conn, subscription)
+	if err != nil {
+		return SubscriptionStatus{}, err
+	}
+	status.Workers, err = getWorkers(ctx, conn, subscription)
+	if err != nil {
+		return SubscriptionStatus{}, err
+	}
+	status.CopyProgress, err = getCopyProgress(ctx, conn, subscription)
+	if err != nil {
+		return SubscriptionStatus{}, err
+	}
+	for _, table := range status.Tables {
+		status.States[table.StateName]++
+		if table.State != readyState {
+			status.Ready = false
+		}
+	}
+
+	log.Info(fmt.Sprintf("Status of subscription %s has been get for database %s, ready: %t", subscription, database, status.Ready))
+	return status, nil
+}
+
+func getTableStates(ctx context.Context, conn postgres.Conn, subscription string) ([]TableState, error) {
+	log := utils.ContextLogger(ctx)
+
+	rows, err := conn.Query(ctx, getSubRelStatesQuery(), subscription)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot get tables state of subscription %s", subscription))
+		return nil, apierror.FromDB(err, "cannot get tables state of subscription %s", subscription)
+	}
+	defer rows.Close()
+
+	tables := make([]TableState, 0)
+	for rows.Next() {
+		var table TableState
+		err = rows.Scan(&table.Schema, &table.Table, &table.State, &table.Lsn)
+		if err != nil {
+			log.Error(fmt.Sprintf("cannot scan tables state of subscription %s", subscription))
+			return nil, apierror.FromDB(err, "cannot scan tables state of subscription %s", subscription)
+		}
+		table.StateName = stateNames[table.State]
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func getWorkers(ctx context.Context, conn postgres.Conn, subscription string) ([]WorkerInfo, error) {
+	log := utils.ContextLogger(ctx)
+
+	rows, err := conn.Query(ctx, getSubWorkersQuery(), subscription)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot get workers of subscription %s", subscription))
+		return nil, apierror.FromDB(err, "cannot get workers of subscription %s", subscription)
+	}
+	defer rows.Close()
+
+	workers := make([]WorkerInfo, 0)
+	for rows.Next() {
+		var worker WorkerInfo
+		err = rows.Scan(&worker.Pid, &worker.Table, &worker.ReceivedLsn, &worker.LatestEndLsn,
+			&worker.LastMsgReceiptTime, &worker.LatestEndTime)
+		if err != nil {
+			log.Error(fmt.Sprintf("cannot scan workers of subscription %s", subscription))
+			return nil, apierror.FromDB(err, "cannot scan workers of subscription %s", subscription)
+		}
+		workers = append(workers, worker)
+	}
+	return workers, nil
+}
+
+func getCopyProgress(ctx context.Context, conn postgres.Conn, subscription string) ([]CopyProgress, error) {
+	log := utils.ContextLogger(ctx)
+
+	rows, err := conn.Query(ctx, getSubCopyProgressQuery(), subscription)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot get copy progress of subscription %s", subscription))
+		return nil, apierror.FromDB(err, "cannot get copy progress of subscription %s", subscription)
+	}
+	defer rows.Close()
+
+	progress := make([]CopyProgress, 0)
+	for rows.Next() {
+		var copyProgress CopyProgress
+		err = rows.Scan(&copyProgress.Pid, &copyProgress.Table, &copyProgress.BytesProcessed,
+			&copyProgress.BytesTotal, &copyProgress.TuplesProcessed)
+		if err != nil {
+			log.Error(fmt.Sprintf("cannot scan copy progress of subscription %s", subscription))
+			return nil, apierror.FromDB(err, "cannot scan copy progress of subscription %s", subscription)
+		}
+		progress = append(progress, copyProgress)
+	}
+	return progress, nil
+}
+
+func (sc *SubscriptionController) createSubscription(ctx context.Context, request SubscriptionRequest) error {
+	log := utils.ContextLogger(ctx)
+
+	subscription := request.SubName
+	database := request.Database
+	err := validateSubscription(subscription, database)
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return err
+	}
+	err = validateCreateRequest(request)
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return err
+	}
+
+	log.Info(fmt.Sprintf("Subscription %s creation started for database %s", subscription, database))
+	exists, err := sc.isSubscriptionExists(ctx, subscription, database)
+	if err != nil {
+		return err
+	}
+	if exists {
+		log.Info(fmt.Sprintf("Subscription %s already exists in database %s", subscription, database))
+		return nil
+	}
+
+	conn, err := sc.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	// Query contains connection string with password, so it is not logged and audited without password
+	audit.AddStatement(ctx, getSubCreateQuery(subscription, redactConnection(request.Connection), request.Publications, request.Options))
+	_, err = conn.Exec(ctx, getSubCreateQuery(subscription, request.Connection, request.Publications, request.Options))
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot create subscription %s for database %s", subscription, database), zap.Error(err))
+		return apierror.FromDB(err, "cannot create subscription %s for database %s", subscription, database)
+	}
+
+	log.Info(fmt.Sprintf("Subscription %s has been created for database %s", subscription, database))
+	return nil
+}
+
+func (sc *SubscriptionController) alterSubscription(ctx context.Context, request SubscriptionRequest) error {
+	log := utils.ContextLogger(ctx)
+
+	subscription := request.SubName
+	database := request.Database
+	err := validateSubscription(subscription, database)
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return err
+	}
+	err = validateOptions(request.Options)
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return err
+	}
+
+	log.Info(fmt.Sprintf("Subscription %s alter started for database %s", subscription, database))
+	exists, err := sc.isSubscriptionExists(ctx, subscription, database)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		log.Info(fmt.Sprintf("Subscription %s doesn't exist in database %s", subscription, database))
+		return apierror.NotFound("subscription %s doesn't exist in database %s", subscription, database)
+	}
+
+	queries := getSubAlterQueries(request)
+	if len(queries) == 0 {
+		err = apierror.BadRequest("nothing to alter in subscription %s in database %s", subscription, database)
+		log.Error(err.Error())
+		return err
+	}
+
+	conn, err := sc.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	for _, query := range queries {
+		log.Debug(query)
+		audit.AddStatement(ctx, query)
+		_, err = conn.Exec(ctx, query)
+		if err != nil {
+			log.Error(fmt.Sprintf("cannot alter subscription %s for database %s", subscription, database), zap.Error(err))
+			return apierror.FromDB(err, "cannot alter subscription %s for database %s", subscription, database)
+		}
+	}
+
+	log.Info(fmt.Sprintf("Subscription %s has been altered for database %s", subscription, database))
+	return nil
+}
+
+func (sc *SubscriptionController) dropSubscription(ctx context.Context, request SubscriptionRequest) error {
+	log := utils.ContextLogger(ctx)
+
+	subscription := request.SubName
+	database := request.Database
+	err := validateSubscription(subscription, database)
+	if err != nil {
+		log.Error(err.Error(), zap.Error(err))
+		return err
+	}
+
+	log.Info(fmt.Sprintf("Subscription %s drop started for database %s", subscription, database))
+	exists, err := sc.isSubscriptionExists(ctx, subscription, database)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		log.Info(fmt.Sprintf("Subscription %s doesn't exist in database %s", subscription, database))
+		return nil
+	}
+
+	conn, err := sc.pgClient.GetConnectionToDb(ctx, database)
+	if err != nil {
+		return apierror.FromConnection(err, "cannot connect to database %s", database)
+	}
+	defer conn.Close(ctx)
+
+	queries := []string{getSubDropQuery(subscription)}
+	if request.KeepSlot {
+		// Detached subscription doesn't drop replication slot on publisher
+		queries = []string{getSubDisableQuery(subscription), getSubDisableSlotQuery(subscription), getSubDropQuery(subscription)}
+	}
+	for _, query := range queries {
+		log.Debug(query)
+		audit.AddStatement(ctx, query)
+		_, err = conn.Exec(ctx, query)
+		if err != nil {
+			log.Error(fmt.Sprintf("cannot drop subscription %s for database %s", subscription, database), zap.Error(err))
+			return apierror.FromDB(err, "cannot drop subscription %s for database %s", subscription, database)
+		}
+	}
+	log.Info(fmt.Sprintf("Subscription %s has been dropped for database %s", subscription, database))
+	return nil
+}
+
+func (sc *SubscriptionController) isSubscriptionExists(ctx context.Context, subscription, database string) (bool, error) {
+	_, err := sc.getSubscriptionInternal(ctx, subscription, database)
+	if apierror.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func scanSubscription(rows pgx.Rows, subInfo *SubscriptionInfo) error {
+	var streaming string
+	err := rows.Scan(&subInfo.Name, &subInfo.Owner, &subInfo.Enabled, &subInfo.Publications,
+		&subInfo.SlotName, &subInfo.Binary, &streaming, &subInfo.Origin)
+	if err != nil {
+		return err
+	}
+	subInfo.Streaming = convStreaming(streaming)
+	return nil
+}
+
+// substream is boolean before PG16 and char since PG16
+func convStreaming(streaming string) string {
+	switch streaming {
+	case "t", "true":
+		return "on"
+	case "p":
+		return "parallel"
+	default:
+		return "off"
+	}
+}
+
+func validateSubscription(subscription, database string) error {
+	if len(database) == 0 {
+		return apierror.BadRequest("database must not be empty")
+	}
+	if len(subscription) == 0 {
+		return apierror.BadRequest("subscription name must not be empty")
+	}
+	return nil
+}
+
+func validateCreateRequest(request SubscriptionRequest) error {
+	if len(request.Connection) == 0 {
+		return apierror.BadRequest("connection must not be empty")
+	}
+	if len(request.Publications) == 0 {
+		return apierror.BadRequest("publications must not be empty")
+	}
+	return validateOptions(request.Options)
+}
+
+func validateOptions(options *SubscriptionOptions) error {
+	if options == nil {
+		return nil
+	}
+	if len(options.Streaming) > 0 && !slices.Contains(streamingValues, options.Streaming) {
+		return apierror.BadRequest("streaming value %s is not supported, allowed values: %s", options.Streaming, strings.Join(streamingValues, ", "))
+	}
+	if len(options.Origin) > 0 && !slices.Contains(originValues, options.Origin) {
+		return apierror.BadRequest("origin value %s is not supported, allowed values: %s", options.Origin, strings.Join(originValues, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subscriptions
+
+import (
+	"context"
+
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SubscriptionRequest struct {
+	SubName      string               `json:"subscriptionName"`
+	Database     string               `json:"database"`
+	Connection   string               `json:"connection,omitempty"`
+	Publications []string             `json:"publications,omitempty"`
+	Options      *SubscriptionOptions `json:"options,omitempty"`
+	Enabled      *bool                `json:"enabled,omitempty"`
+	Refresh      bool                 `json:"refresh,omitempty"`
+	KeepSlot     bool                 `json:"keepSlot,omitempty"`
+}
+
+func (sc *SubscriptionController) SubscriptionListHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
+	subscriptions, err := sc.listSubscriptions(ctx, c.Params("database"))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(subscriptions)
+}
+
+func (sc *SubscriptionController) SubscriptionGetHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
+	database := c.Params("database")
+	subscription := c.Params("subscription")
+	err := validateSubscription(subscription, database)
+	if err != nil {
+		return err
+	}
+
+	subInfo, err := sc.getSubscriptionInternal(ctx, subscription, database)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(subInfo)
+}
+
+func (sc *SubscriptionController) SubscriptionStatusHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
+	status, err := sc.getSubscriptionStatus(ctx, c.Params("subscription"), c.Params("database"))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(status)
+}
+
+func (sc *SubscriptionController) SubscriptionCreateHandler(c *fiber.Ctx) error {
+	return handleCommonFunc(c, sc.createSubscription)
+}
+
+func (sc *SubscriptionController) SubscriptionAlterHandler(c *fiber.Ctx) error {
+	return handleCommonFunc(c, sc.alterSubscription)
+}
+
+func (sc *SubscriptionController) SubscriptionDropHandler(c *fiber.Ctx) error {
+	return handleCommonFunc(c, sc.dropSubscription)
+}
+
+func handleCommonFunc(c *fiber.Ctx, handleFunc func(context.Context, SubscriptionRequest) error) error {
+	ctx := utils.GetRequestContext(c)
+	request, err := getSubscriptionReq(c)
+	if err != nil {
+		return err
+	}
+	err = handleFunc(ctx, request)
+	if err != nil {
+		return err
+	}
+	return ok(c)
+}
+
+func getSubscriptionReq(c *fiber.Ctx) (SubscriptionRequest, error) {
+	var request SubscriptionRequest
+	if len(c.Body()) > 0 {
+		err := c.BodyParser(&request)
+		if err != nil {
+			return request, apierror.BadRequest("cannot parse request: %s", err.Error())
+		}
+	}
+	return request, nil
+}
+
+func ok(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).SendString("OK")
+}
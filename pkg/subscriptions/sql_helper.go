@@ -0,0 +1,193 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subscriptions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// pg_subscription and pg_stat_subscription are cluster wide, so subscription is looked up in current database
+	currentSubIdQuery      = "select oid from pg_subscription where subname = $1 and subdbid = (select oid from pg_database where datname = current_database())"
+	subListQuery           = "select subname, pg_get_userbyid(subowner), subenabled, subpublications, coalesce(subslotname, ''), subbinary, substream::text, %s from pg_subscription where subdbid = (select oid from pg_database where datname = current_database()) order by subname"
+	subGetQuery            = "select subname, pg_get_userbyid(subowner), subenabled, subpublications, coalesce(subslotname, ''), subbinary, substream::text, %s from pg_subscription where subdbid = (select oid from pg_database where datname = current_database()) and subname = $1"
+	subRelStatesQuery      = "select n.nspname, c.relname, sr.srsubstate::text, coalesce(sr.srsublsn::text, '') from pg_subscription_rel sr join pg_subscription s on s.oid = sr.srsubid join pg_class c on c.oid = sr.srrelid join pg_namespace n on n.oid = c.relnamespace where s.oid = (" + currentSubIdQuery + ") order by 1, 2"
+	subWorkersQuery        = "select pid, coalesce(relid::regclass::text, ''), coalesce(received_lsn::text, ''), coalesce(latest_end_lsn::text, ''), last_msg_receipt_time, latest_end_time from pg_stat_subscription where subid = (" + currentSubIdQuery + ") order by relid nulls first"
+	subCopyProgressQuery   = "select pid, relid::regclass::text, bytes_processed, bytes_total, tuples_processed from pg_stat_progress_copy where pid in (select pid from pg_stat_subscription where subid = (" + currentSubIdQuery + ") and relid is not null)"
+	subCreateQuery         = "CREATE SUBSCRIPTION %s CONNECTION %s PUBLICATION %s"
+	subEnableQuery         = "ALTER SUBSCRIPTION %s ENABLE"
+	subDisableQuery        = "ALTER SUBSCRIPTION %s DISABLE"
+	subSetPublicationQuery = "ALTER SUBSCRIPTION %s SET PUBLICATION %s"
+	subRefreshQuery        = "ALTER SUBSCRIPTION %s REFRESH PUBLICATION"
+	subSetOptionsQuery     = "ALTER SUBSCRIPTION %s SET (%s)"
+	subDropQuery           = "DROP SUBSCRIPTION IF EXISTS %s"
+	subDisableSlotQuery    = "ALTER SUBSCRIPTION %s SET (slot_name = NONE)"
+	streamingOption        = "streaming"
+	binaryOption           = "binary"
+	originOption           = "origin"
+	copyDataOption         = "copy_data"
+	createSlotOption       = "create_slot"
+	enabledOption          = "enabled"
+	slotNameOption         = "slot_name"
+	refreshOption          = "refresh"
+
+	// suborigin exists since PG16, older servers replicate changes of any origin
+	originColumn        = "suborigin"
+	originAbsent        = "'any'"
+	originColumnVersion = 160000
+)
+
+var (
+	passwordRegexp = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+	// Password of URI is either in userinfo or in password parameter
+	uriUserInfoRegexp = regexp.MustCompile(`^(postgres(?:ql)?://[^:/@?#]*:)([^/?#]*)@`)
+	uriPasswordRegexp = regexp.MustCompile(`([?&]password=)([^&#]*)`)
+)
+
+func getSubListQuery(serverVersion int) string {
+	return fmt.Sprintf(subListQuery, getOriginColumn(serverVersion))
+}
+
+func getSubGetQuery(serverVersion int) string {
+	return fmt.Sprintf(subGetQuery, getOriginColumn(serverVersion))
+}
+
+func getOriginColumn(serverVersion int) string {
+	if serverVersion >= originColumnVersion {
+		return originColumn
+	}
+	return originAbsent
+}
+
+func getSubRelStatesQuery() string {
+	return subRelStatesQuery
+}
+
+func getSubWorkersQuery() string {
+	return subWorkersQuery
+}
+
+func getSubCopyProgressQuery() string {
+	return subCopyProgressQuery
+}
+
+func getSubCreateQuery(subscription, connection string, publications []string, options *SubscriptionOptions) string {
+	query := fmt.Sprintf(subCreateQuery, quoteIdent(subscription), quoteLiteral(connection), prepareIdents(publications))
+	params := formOptionsParams(options, true)
+	if len(params) > 0 {
+		query = fmt.Sprintf("%s WITH (%s)", query, strings.Join(params, ", "))
+	}
+	return query
+}
+
+// Statements of ALTER SUBSCRIPTION must be executed separately,
+// because some of them are not allowed in transaction block
+func getSubAlterQueries(request SubscriptionRequest) []string {
+	subscription := quoteIdent(request.SubName)
+	queries := make([]string, 0)
+	if request.Enabled != nil && !*request.Enabled {
+		queries = append(queries, fmt.Sprintf(subDisableQuery, subscription))
+	}
+	if params := formOptionsParams(request.Options, false); len(params) > 0 {
+		queries = append(queries, fmt.Sprintf(subSetOptionsQuery, subscription, strings.Join(params, ", ")))
+	}
+	if len(request.Publications) > 0 {
+		query := fmt.Sprintf(subSetPublicationQuery, subscription, prepareIdents(request.Publications))
+		if !request.Refresh {
+			query = fmt.Sprintf("%s WITH (%s = false)", query, refreshOption)
+		}
+		queries = append(queries, query)
+	} else if request.Refresh {
+		queries = append(queries, fmt.Sprintf(subRefreshQuery, subscription))
+	}
+	if request.Enabled != nil && *request.Enabled {
+		queries = append(queries, fmt.Sprintf(subEnableQuery, subscription))
+	}
+	return queries
+}
+
+func getSubDisableQuery(subscription string) string {
+	return fmt.Sprintf(subDisableQuery, quoteIdent(subscription))
+}
+
+func getSubDisableSlotQuery(subscription string) string {
+	return fmt.Sprintf(subDisableSlotQuery, quoteIdent(subscription))
+}
+
+func getSubDropQuery(subscription string) string {
+	return fmt.Sprintf(subDropQuery, quoteIdent(subscription))
+}
+
+func formOptionsParams(options *SubscriptionOptions, forCreate bool) []string {
+	params := make([]string, 0)
+	if options == nil {
+		return params
+	}
+	if len(options.Streaming) > 0 {
+		params = append(params, fmt.Sprintf("%s = %s", streamingOption, options.Streaming))
+	}
+	if options.Binary != nil {
+		params = append(params, fmt.Sprintf("%s = %t", binaryOption, *options.Binary))
+	}
+	if len(options.Origin) > 0 {
+		params = append(params, fmt.Sprintf("%s = %s", originOption, quoteLiteral(options.Origin)))
+	}
+	if !forCreate {
+		return params
+	}
+	if options.CopyData != nil {
+		params = append(params, fmt.Sprintf("%s = %t", copyDataOption, *options.CopyData))
+	}
+	if options.CreateSlot != nil {
+		params = append(params, fmt.Sprintf("%s = %t", createSlotOption, *options.CreateSlot))
+	}
+	if options.Enabled != nil {
+		params = append(params, fmt.Sprintf("%s = %t", enabledOption, *options.Enabled))
+	}
+	if len(options.SlotName) > 0 {
+		params = append(params, fmt.Sprintf("%s = %s", slotNameOption, quoteLiteral(options.SlotName)))
+	}
+	return params
+}
+
+func prepareIdents(names []string) string {
+	prepared := make([]string, 0, len(names))
+	for _, name := range names {
+		prepared = append(prepared, quoteIdent(name))
+	}
+	return strings.Join(prepared, ", ")
+}
+
+func quoteIdent(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+// Replaces password of connection string, so query can be recorded.
+// Both keyword/value and URI forms of connection string are supported
+func redactConnection(connection string) string {
+	if strings.HasPrefix(connection, "postgres://") || strings.HasPrefix(connection, "postgresql://") {
+		connection = uriUserInfoRegexp.ReplaceAllString(connection, "${1}***@")
+		return uriPasswordRegexp.ReplaceAllString(connection, "${1}***")
+	}
+	return passwordRegexp.ReplaceAllString(connection, "${1}***")
+}
+
+func quoteLiteral(value string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(value, "'", "''"))
+}
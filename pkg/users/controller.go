@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/audit"
 	"github.com/Netcracker/pgskipper-replication-controller/pkg/postgres"
 	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
 	"go.uber.org/zap"
@@ -27,6 +29,13 @@ type UsersController struct {
 	pgClient *postgres.Client
 }
 
+// Plan is SQL which would be executed for request along with checks
+// performed before execution, it is formed without database access
+type Plan struct {
+	Statements []string `json:"statements"`
+	Checks     []string `json:"checks"`
+}
+
 func NewUsersController(pgClient *postgres.Client) *UsersController {
 	return &UsersController{pgClient: pgClient}
 }
@@ -42,22 +51,34 @@ func (pc *UsersController) grantUserToReplication(ctx context.Context, request U
 
 	conn, err := pc.pgClient.GetConnection(ctx)
 	if err != nil {
-		panic(err)
+		return apierror.FromConnection(err, "cannot connect to postgres")
 	}
 	defer conn.Close(ctx)
 
+	audit.AddStatement(ctx, getGrantReplicationQuery(username))
 	_, err = conn.Exec(ctx, getGrantReplicationQuery(username))
 	if err != nil {
-		log.Error(fmt.Sprintf("cannot grant user %s for Replication", username))
-		panic(err)
+		log.Error(fmt.Sprintf("cannot grant user %s for Replication", username), zap.Error(err))
+		return apierror.FromDB(err, "cannot grant user %s for Replication", username)
 	}
 	log.Info(fmt.Sprintf("User %s has been granted for Replication", username))
 	return nil
 }
 
+func planGrantUserToReplication(request UserRequest) (Plan, error) {
+	err := validateGrantRequest(request.Username)
+	if err != nil {
+		return Plan{}, err
+	}
+	return Plan{
+		Statements: []string{getGrantReplicationQuery(request.Username)},
+		Checks:     []string{fmt.Sprintf("user %s exists", request.Username)},
+	}, nil
+}
+
 func validateGrantRequest(username string) error {
 	if len(username) == 0 {
-		return fmt.Errorf("username must not be empty")
+		return apierror.BadRequest("username must not be empty")
 	}
 	return nil
 }
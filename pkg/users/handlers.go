@@ -15,6 +15,7 @@
 package users
 
 import (
+	"github.com/Netcracker/pgskipper-replication-controller/pkg/apierror"
 	"github.com/Netcracker/pgskipper-replication-controller/pkg/utils"
 	"github.com/gofiber/fiber/v2"
 )
@@ -24,14 +25,25 @@ type UserRequest struct {
 }
 
 func (pc *UsersController) GrantUserHandler(c *fiber.Ctx) error {
+	ctx := utils.GetRequestContext(c)
 	request, err := getUserReq(c)
 	if err != nil {
 		return err
 	}
-	ctx := utils.GetRequestContext(c)
+	dryRun, err := utils.GetQueryBoolParam(c, "dryRun")
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		plan, err := planGrantUserToReplication(request)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(plan)
+	}
 	err = pc.grantUserToReplication(ctx, request)
 	if err != nil {
-		return badReq(c, err)
+		return err
 	}
 	return ok(c)
 }
@@ -41,16 +53,12 @@ func getUserReq(c *fiber.Ctx) (UserRequest, error) {
 	if len(c.Body()) > 0 {
 		err := c.BodyParser(&request)
 		if err != nil {
-			return request, err
+			return request, apierror.BadRequest("cannot parse request: %s", err.Error())
 		}
 	}
 	return request, nil
 }
 
-func badReq(c *fiber.Ctx, err error) error {
-	return c.Status(fiber.StatusBadRequest).SendString(err.Error())
-}
-
 func ok(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).SendString("OK")
 }
@@ -16,6 +16,7 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -34,6 +35,8 @@ var (
 
 type RequestId string
 
+type Principal string
+
 func init() {
 	log = GetLogger()
 }
@@ -89,7 +92,11 @@ func GetEnvBool(key string, fallback bool) bool {
 
 func ContextLogger(ctx context.Context) *zap.Logger {
 	logger := GetLogger()
-	return logger.With(zap.ByteString("request_id", []byte(fmt.Sprintf("%s", ctx.Value(RequestId("request_id"))))))
+	logger = logger.With(zap.ByteString("request_id", []byte(fmt.Sprintf("%s", ctx.Value(RequestId("request_id"))))))
+	if principal, ok := ctx.Value(Principal("principal")).(string); ok && len(principal) > 0 {
+		logger = logger.With(zap.String("principal", principal))
+	}
+	return logger
 }
 
 func GetRequestContext(c *fiber.Ctx) context.Context {
@@ -100,11 +107,63 @@ func GetRequestContext(c *fiber.Ctx) context.Context {
 		requestId = []byte(id)
 	}
 
-	bg := context.Background()
-	ctx := context.WithValue(bg, RequestId("request_id"), requestId)
+	// User context may carry values of middlewares, e.g. audit record
+	ctx := context.WithValue(c.UserContext(), RequestId("request_id"), requestId)
+	ctx = context.WithValue(ctx, Principal("principal"), GetPrincipal(c))
 	return ctx
 }
 
+// DefaultCluster is configured with controller flags and serves routes without cluster id
+const DefaultCluster = "default"
+
+// GetRequestCluster returns cluster id of route, routes without cluster id are served by default cluster
+func GetRequestCluster(c *fiber.Ctx) string {
+	if cluster := c.Params("cluster"); len(cluster) > 0 {
+		return cluster
+	}
+	return DefaultCluster
+}
+
+// GetRequestDatabase returns database of request from route params,
+// JSON body or query, route params are available only in route handlers.
+// Handlers act on database of body, so it must not differ from query
+func GetRequestDatabase(c *fiber.Ctx) (string, error) {
+	if database := c.Params("database"); len(database) > 0 {
+		return database, nil
+	}
+	var body struct {
+		Database string `json:"database"`
+	}
+	if len(c.Body()) > 0 {
+		_ = json.Unmarshal(c.Body(), &body)
+	}
+	query := c.Query("database")
+	if len(body.Database) > 0 && len(query) > 0 && body.Database != query {
+		return "", fmt.Errorf("database %s of query differs from database %s of request body", query, body.Database)
+	}
+	if len(body.Database) > 0 {
+		return body.Database, nil
+	}
+	return query, nil
+}
+
+// GetQueryBoolParam returns bool query param of request, absent param is false
+func GetQueryBoolParam(c *fiber.Ctx, param string) (bool, error) {
+	paramStr := c.Query(param, "false")
+	boolVal, err := strconv.ParseBool(paramStr)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot parse bool value for param %s", param), zap.Error(err))
+		return false, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("cannot parse bool value for param %s", param))
+	}
+	return boolVal, nil
+}
+
+// GetPrincipal returns name of authenticated user of request
+func GetPrincipal(c *fiber.Ctx) string {
+	principal, _ := c.Locals("username").(string)
+	return principal
+}
+
 func IsHttpsEnabled() bool {
 	return GetEnv("TLS_ENABLED", "false") == "true"
 }